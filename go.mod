@@ -2,4 +2,10 @@ module wget
 
 go 1.20
 
-require golang.org/x/net v0.17.0
+require (
+	golang.org/x/crypto v0.15.0
+	golang.org/x/net v0.17.0
+	golang.org/x/term v0.14.0
+)
+
+require golang.org/x/sys v0.14.0 // indirect
@@ -0,0 +1,272 @@
+package wget
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// credentials is a user name and password used to answer an authentication challenge.
+type credentials struct {
+	user     string
+	password string
+}
+
+// authChallenge is a challenge answered successfully for a host, reused to authenticate
+// the following requests to that host without waiting for a new 401.
+type authChallenge struct {
+	scheme string
+	params map[string]string
+	creds  credentials
+	count  int
+}
+
+var (
+	authMu    sync.Mutex
+	authCache = make(map[string]*authChallenge)
+)
+
+// credentialsFor returns the credentials to use for host.
+//
+// The user information of the URL and the --user and --password options only apply to
+// originHost, the host of the URL as requested, so that they are never sent to another
+// host after a redirect. Any host can still be matched in the .netrc file.
+func credentialsFor(host, originHost string, userinfo *url.Userinfo) (credentials, bool) {
+	if host == originHost {
+		if userinfo != nil {
+			password, _ := userinfo.Password()
+			return credentials{userinfo.Username(), password}, true
+		}
+		if User != "" {
+			return credentials{User, Password}, true
+		}
+	}
+	return netrcCredentials(host)
+}
+
+// authorize adds an Authorization header to req when a challenge of its host was already answered.
+func authorize(req *http.Request) {
+	authMu.Lock()
+	defer authMu.Unlock()
+	challenge, ok := authCache[req.URL.Host]
+	if !ok {
+		return
+	}
+	req.Header.Set("Authorization", challenge.answer(req))
+}
+
+// answerChallenge builds a new request answering the 401 response with the credentials
+// of its host. It returns nil when the challenge cannot be answered.
+func answerChallenge(resp *http.Response, originHost string, userinfo *url.Userinfo) *http.Request {
+	host := resp.Request.URL.Host
+	creds, ok := credentialsFor(host, originHost, userinfo)
+	if !ok || resp.Request.Header.Get("Authorization") != "" && !isStale(resp) {
+		return nil
+	}
+	challenge := selectChallenge(resp.Header.Values("WWW-Authenticate"))
+	if challenge == nil {
+		return nil
+	}
+	challenge.creds = creds
+
+	req := resp.Request.Clone(resp.Request.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil
+		}
+		req.Body = body
+	}
+	authMu.Lock()
+	authCache[host] = challenge
+	req.Header.Set("Authorization", challenge.answer(req))
+	authMu.Unlock()
+	return req
+}
+
+// isStale reports whether a Digest challenge only rejected an outdated nonce.
+func isStale(resp *http.Response) bool {
+	challenge := selectChallenge(resp.Header.Values("WWW-Authenticate"))
+	return challenge != nil && strings.EqualFold(challenge.params["stale"], "true")
+}
+
+// selectChallenge picks the strongest supported challenge of the WWW-Authenticate headers:
+// Digest with SHA-256, then Digest with MD5, then Basic.
+func selectChallenge(headers []string) *authChallenge {
+	var best *authChallenge
+	rank := func(c *authChallenge) int {
+		if c.scheme == "basic" {
+			return 1
+		}
+		if strings.HasPrefix(strings.ToUpper(c.params["algorithm"]), "SHA-256") {
+			return 3
+		}
+		return 2
+	}
+	for _, header := range headers {
+		c := parseChallenge(header)
+		if c == nil {
+			continue
+		}
+		if c.scheme == "digest" && digestHash(c.params["algorithm"]) == nil {
+			continue
+		}
+		if best == nil || rank(c) > rank(best) {
+			best = c
+		}
+	}
+	return best
+}
+
+// parseChallenge parses a WWW-Authenticate header such as `Digest realm="x", nonce="y"`.
+// It returns nil for schemes other than Basic and Digest.
+func parseChallenge(header string) *authChallenge {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	scheme = strings.ToLower(scheme)
+	if scheme != "basic" && scheme != "digest" {
+		return nil
+	}
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			var quoted strings.Builder
+			end := 1
+			for ; end < len(value) && value[end] != '"'; end++ {
+				if value[end] == '\\' && end+1 < len(value) {
+					end++
+				}
+				quoted.WriteByte(value[end])
+			}
+			params[key] = quoted.String()
+			rest = ""
+			if end+1 < len(value) {
+				rest = value[end+1:]
+			}
+		} else {
+			v, next, _ := strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(v)
+			rest = "," + next
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ","))
+	}
+	return &authChallenge{scheme: scheme, params: params}
+}
+
+// answer returns the Authorization header value answering the challenge for req.
+// The caller must hold authMu, as Digest answers count the uses of the nonce.
+func (c *authChallenge) answer(req *http.Request) string {
+	if c.scheme == "basic" {
+		r := &http.Request{Header: make(http.Header)}
+		r.SetBasicAuth(c.creds.user, c.creds.password)
+		return r.Header.Get("Authorization")
+	}
+	return c.digest(req.Method, req.URL.RequestURI())
+}
+
+// digest computes a Digest authorization (RFC 7616) for the method and request URI.
+func (c *authChallenge) digest(method, uri string) string {
+	algorithm := c.params["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	newHash := digestHash(algorithm)
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	realm, nonce := c.params["realm"], c.params["nonce"]
+	c.count++
+	nc := fmt.Sprintf("%08x", c.count)
+	cnonceBytes := make([]byte, 16)
+	rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+
+	ha1 := h(c.creds.user + ":" + realm + ":" + c.creds.password)
+	if strings.HasSuffix(strings.ToLower(algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	qop := ""
+	for _, option := range strings.Split(c.params["qop"], ",") {
+		if strings.TrimSpace(option) == "auth" {
+			qop = "auth"
+		}
+	}
+	var response string
+	if qop != "" {
+		response = h(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		c.creds.user, realm, nonce, uri, algorithm, response)
+	if qop != "" {
+		header += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, nc, cnonce)
+	}
+	if opaque, ok := c.params["opaque"]; ok {
+		header += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+	return header
+}
+
+// digestHash returns the hash function of a Digest algorithm, or nil when it is not supported.
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), "-sess")) {
+	case "", "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	}
+	return nil
+}
+
+// AskPassword prompts for the password of --user on the terminal, without echoing it.
+func AskPassword() error {
+	user := User
+	if user == "" {
+		user = "<none>"
+	}
+	fmt.Printf("Password for user ‘%s’: ", user)
+
+	// The typed password is hidden on a terminal, a piped one is read as a line
+	fd := int(os.Stdin.Fd())
+	var password string
+	if term.IsTerminal(fd) {
+		typed, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("error reading password: %v", err)
+		}
+		password = string(typed)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		fmt.Println()
+		if err != nil && line == "" {
+			return fmt.Errorf("error reading password: %v", err)
+		}
+		password = line
+	}
+	Password = strings.TrimRight(password, "\r\n")
+	return nil
+}
@@ -0,0 +1,130 @@
+package wget
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// DownloadResult describes the outcome of one download of an -i batch.
+type DownloadResult struct {
+	URL      string
+	FilePath string
+	Size     int
+	Duration time.Duration
+	Skipped  bool // rejected, or not retrieved because the local copy is current
+	Err      error
+}
+
+// DownloadConcurrently downloads every URL of an -i input file with at most MaxConcurrent
+// transfers running at the same time.
+//
+// While the batch runs, a progress board shows one bar per active transfer and an aggregate
+// bar, unless the output goes to the log file or is not a terminal. The returned results are
+// in the same order as urls.
+func DownloadConcurrently(urls []string, output, downloadPath string, reject []string, logFile bool, rateLimit int) []DownloadResult {
+	// Each URL may come from a different host, so no domain restriction applies
+	Domain = ""
+
+	workers := MaxConcurrent
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(urls) {
+		workers = len(urls)
+	}
+
+	stop := make(chan struct{})
+	drawn := make(chan struct{})
+	if !logFile && isTerminal() {
+		board = newProgressBoard(len(urls))
+		go func() {
+			board.run(stop)
+			close(drawn)
+		}()
+	} else {
+		close(drawn)
+	}
+
+	results := make([]DownloadResult, len(urls))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j] = downloadOne(urls[j], output, downloadPath, reject, logFile, rateLimit)
+				board.finish(urls[j])
+			}
+		}()
+	}
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	close(stop)
+	<-drawn
+	board = nil
+	return results
+}
+
+// downloadOne downloads a single URL of an -i batch and measures the result.
+func downloadOne(url, output, downloadPath string, reject []string, logFile bool, rateLimit int) DownloadResult {
+	fileName, _ := GetFilenameAndDirFromURL(url)
+	if output != "" {
+		fileName = output
+	}
+	result := DownloadResult{URL: url}
+
+	startTime := time.Now()
+	_, skipped, err := downloadResource(url, fileName, downloadPath, reject, logFile, rateLimit, true)
+	result.Duration = time.Since(startTime)
+	result.Err = err
+	if err == nil && skipped {
+		result.Skipped = true
+		return result
+	}
+	if filePath, ok := savedPath(url); ok {
+		result.FilePath = filePath
+	} else {
+		dir, _ := expandTilde(downloadPath)
+		result.FilePath = path.Join(dir, fileName)
+	}
+	if info, err := os.Stat(result.FilePath); err == nil {
+		result.Size = int(info.Size())
+	}
+	return result
+}
+
+// PrintSummary prints a table with the outcome of every download of an -i batch.
+func PrintSummary(results []DownloadResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tFILE\tSIZE\tTIME\tSTATUS")
+	failed, skipped := 0, 0
+	for _, result := range results {
+		status := "ok"
+		switch {
+		case result.Err != nil:
+			status = "error: " + result.Err.Error()
+			failed++
+		case result.Skipped:
+			status = "skipped"
+			skipped++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			result.URL,
+			result.FilePath,
+			FormatFileSize(result.Size),
+			result.Duration.Truncate(time.Millisecond),
+			status,
+		)
+	}
+	w.Flush()
+	fmt.Printf("\n%d downloaded, %d skipped, %d failed\n", len(results)-failed-skipped, skipped, failed)
+}
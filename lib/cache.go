@@ -0,0 +1,170 @@
+package wget
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheEntry is what the cache remembers about a downloaded URL.
+type cacheEntry struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Expires      time.Time `json:"expires"`
+	FilePath     string    `json:"file"`
+	Size         int64     `json:"size"`
+}
+
+// httpCache is the on-disk store of validators and expiry dates enabled by --cache.
+//
+// Fresh entries are used without contacting the server, stale ones are revalidated with
+// If-None-Match and If-Modified-Since so that a 304 Not Modified keeps the local copy.
+type httpCache struct {
+	mu      sync.Mutex
+	once    sync.Once
+	entries map[string]*cacheEntry
+}
+
+var cache = &httpCache{}
+
+// load reads the cache file the first time the cache is used.
+func (c *httpCache) load() {
+	c.once.Do(func() {
+		c.entries = make(map[string]*cacheEntry)
+		data, err := os.ReadFile(CacheFile)
+		if err != nil {
+			return
+		}
+		if err := json.Unmarshal(data, &c.entries); err != nil {
+			fmt.Printf("Ignoring invalid cache file %s: %v\n", CacheFile, err)
+			c.entries = make(map[string]*cacheEntry)
+		}
+	})
+}
+
+// lookup returns the entry of url when its local copy is still on disk with the same size.
+func (c *httpCache) lookup(url string) (*cacheEntry, bool) {
+	if !UseCache {
+		return nil, false
+	}
+	c.load()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	info, err := os.Stat(entry.FilePath)
+	if err != nil || info.Size() != entry.Size {
+		return nil, false
+	}
+	return entry, true
+}
+
+// fresh returns the entry of url when it has not expired yet.
+func (c *httpCache) fresh(url string) (*cacheEntry, bool) {
+	entry, ok := c.lookup(url)
+	if !ok || !time.Now().Before(entry.Expires) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// addValidators adds the conditional headers of the cached copy of url to the request headers.
+func (c *httpCache) addValidators(url string, header http.Header) {
+	entry, ok := c.lookup(url)
+	if !ok {
+		return
+	}
+	if entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// store records the response of url saved at filePath with the given size.
+// Responses marked no-store are forgotten instead.
+func (c *httpCache) store(url, filePath string, resp *http.Response, size int) {
+	if !UseCache {
+		return
+	}
+	c.load()
+	expires, cacheable := cacheExpiry(resp)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !cacheable {
+		delete(c.entries, url)
+		return
+	}
+	c.entries[url] = &cacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Expires:      expires,
+		FilePath:     filePath,
+		Size:         int64(size),
+	}
+}
+
+// refresh updates the expiry of url after the server answered 304 Not Modified.
+func (c *httpCache) refresh(url string, resp *http.Response) {
+	c.load()
+	expires, _ := cacheExpiry(resp)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[url]; ok {
+		entry.Expires = expires
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			entry.ETag = etag
+		}
+	}
+}
+
+// SaveCache writes the cache to the --cache-file at the end of the run.
+func SaveCache() error {
+	if !UseCache || cache.entries == nil {
+		return nil
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	data, err := json.MarshalIndent(cache.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(CacheFile, data, 0644)
+}
+
+// cacheExpiry returns until when a response may be used without revalidation, from its
+// Cache-Control max-age (minus its Age) or Expires header. The second value is false for
+// no-store responses, which must not be cached at all. no-cache responses expire at once.
+func cacheExpiry(resp *http.Response) (time.Time, bool) {
+	now := time.Now()
+	maxAge := -1
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			return now, false
+		case "no-cache":
+			return now, true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				maxAge = seconds
+			}
+		}
+	}
+	if maxAge >= 0 {
+		age, _ := strconv.Atoi(resp.Header.Get("Age"))
+		return now.Add(time.Duration(maxAge-age) * time.Second), true
+	}
+	if expires, err := http.ParseTime(resp.Header.Get("Expires")); err == nil {
+		return expires, true
+	}
+	return now, true
+}
@@ -0,0 +1,327 @@
+package wget
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// hashAlgorithms are the digests supported by --checksum, --checksums and --write-sums.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// expectedSum is a digest a downloaded file must match.
+type expectedSum struct {
+	algorithm string
+	digest    string
+}
+
+var (
+	checksumsOnce sync.Once
+	checksumsErr  error
+	// expectedSums holds the --checksums entries by file name
+	expectedSums map[string]expectedSum
+
+	// checksumURL is the URL the --checksum digest is checked against
+	checksumURL string
+
+	sumsMu sync.Mutex
+	// writtenSums holds the digests of the files downloaded in this run, by file path
+	writtenSums = make(map[string]string)
+)
+
+// fileHasher computes the digests of a download while it is written, for every algorithm
+// the file is checked against or listed with.
+type fileHasher struct {
+	hashes map[string]hash.Hash
+}
+
+// newFileHasher returns a hasher for the algorithms required by the checksum options.
+func newFileHasher() (*fileHasher, error) {
+	h := &fileHasher{hashes: make(map[string]hash.Hash)}
+	var algorithms []string
+	if WriteSums != "" {
+		algorithms = append(algorithms, SumsAlgorithm)
+	}
+	if Checksum != "" || ChecksumsFile != "" {
+		err := loadChecksums()
+		if err != nil {
+			return nil, err
+		}
+		for _, sum := range expectedSums {
+			algorithms = append(algorithms, sum.algorithm)
+		}
+	}
+	for _, algorithm := range algorithms {
+		newHash, ok := hashAlgorithms[algorithm]
+		if !ok {
+			return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+		}
+		if _, ok := h.hashes[algorithm]; !ok {
+			h.hashes[algorithm] = newHash()
+		}
+	}
+	return h, nil
+}
+
+// Write adds p to every digest.
+func (h *fileHasher) Write(p []byte) (int, error) {
+	for _, digest := range h.hashes {
+		digest.Write(p)
+	}
+	return len(p), nil
+}
+
+// Reset discards what was hashed, when a download starts over.
+func (h *fileHasher) Reset() {
+	for _, digest := range h.hashes {
+		digest.Reset()
+	}
+}
+
+// hashFile adds the first size bytes of a file, such as the part of a resumed download
+// that is already on disk. A negative size hashes the whole file.
+func (h *fileHasher) hashFile(filePath string, size int) error {
+	if len(h.hashes) == 0 {
+		return nil
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if size >= 0 {
+		reader = io.LimitReader(file, int64(size))
+	}
+	_, err = io.Copy(h, reader)
+	return err
+}
+
+// sum returns the hexadecimal digest of an algorithm.
+func (h *fileHasher) sum(algorithm string) string {
+	return hex.EncodeToString(h.hashes[algorithm].Sum(nil))
+}
+
+// verifyChecksum checks a completed download against its expected digest, if it has one.
+//
+// A mismatching file is quarantined as name.bad instead of taking its real name.
+func verifyChecksum(h *fileHasher, url, fileName, tempPath, filePath string) error {
+	expected, ok := expectedChecksum(url, fileName)
+	if !ok {
+		return nil
+	}
+	actual := h.sum(expected.algorithm)
+	if actual == expected.digest {
+		return nil
+	}
+	badPath := filePath + ".bad"
+	err := os.Rename(tempPath, badPath)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s checksum mismatch for %s: expected %s, got %s (kept as %s)", expected.algorithm, fileName, expected.digest, actual, badPath)
+}
+
+// verifyExistingFile checks a file kept instead of being downloaded again, with -nc, -N, -c
+// or --cache, against its expected digest, if it has one. A mismatching file is left in place.
+func verifyExistingFile(url, fileName, filePath string) error {
+	if Checksum == "" && ChecksumsFile == "" {
+		return nil
+	}
+	h, err := newFileHasher()
+	if err != nil {
+		return err
+	}
+	expected, ok := expectedChecksum(url, fileName)
+	if !ok {
+		return nil
+	}
+	err = h.hashFile(filePath, -1)
+	if err != nil {
+		return err
+	}
+	if actual := h.sum(expected.algorithm); actual != expected.digest {
+		return fmt.Errorf("%s checksum mismatch for the existing %s: expected %s, got %s", expected.algorithm, filePath, expected.digest, actual)
+	}
+	return nil
+}
+
+// expectedChecksum returns the digest a file must match, by its name in --checksums or,
+// for the URL given with it, from --checksum.
+func expectedChecksum(url, fileName string) (expectedSum, bool) {
+	if Checksum == "" && ChecksumsFile == "" {
+		return expectedSum{}, false
+	}
+	expected, ok := expectedSums[path.Clean(fileName)]
+	if !ok {
+		expected, ok = expectedSums[path.Base(fileName)]
+	}
+	if !ok && url == checksumURL {
+		expected, ok = expectedSums[""]
+	}
+	return expected, ok
+}
+
+// recordSum remembers the digest of a downloaded file for --write-sums.
+func recordSum(h *fileHasher, filePath string) {
+	if WriteSums == "" {
+		return
+	}
+	sumsMu.Lock()
+	defer sumsMu.Unlock()
+	writtenSums[filePath] = h.sum(SumsAlgorithm)
+}
+
+// loadChecksums parses --checksum and the --checksums file the first time they are needed.
+func loadChecksums() error {
+	checksumsOnce.Do(func() {
+		expectedSums = make(map[string]expectedSum)
+		if Checksum != "" {
+			// The digest of the single URL given is stored under the empty name
+			algorithm, digest, ok := strings.Cut(Checksum, "=")
+			if !ok {
+				checksumsErr = fmt.Errorf("invalid checksum %q, expected ALGORITHM=DIGEST", Checksum)
+				return
+			}
+			sum, err := newExpectedSum(strings.ToLower(algorithm), digest)
+			if err != nil {
+				checksumsErr = err
+				return
+			}
+			expectedSums[""] = sum
+		}
+		if ChecksumsFile != "" {
+			checksumsErr = readChecksums(ChecksumsFile)
+		}
+	})
+	return checksumsErr
+}
+
+// readChecksums reads a file in the format of sha256sum and friends, "DIGEST  NAME" per line.
+// The algorithm is told by the length of the digest.
+func readChecksums(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		digest, name, ok := strings.Cut(text, " ")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected a digest and a file name", file, line)
+		}
+		// "*" marks files hashed in binary mode
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		var algorithm string
+		switch len(digest) {
+		case 32:
+			algorithm = "md5"
+		case 40:
+			algorithm = "sha1"
+		case 64:
+			algorithm = "sha256"
+		case 128:
+			algorithm = "sha512"
+		}
+		sum, err := newExpectedSum(algorithm, digest)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", file, line, err)
+		}
+		expectedSums[path.Clean(name)] = sum
+	}
+	return scanner.Err()
+}
+
+// newExpectedSum validates a digest of an algorithm.
+func newExpectedSum(algorithm, digest string) (expectedSum, error) {
+	newHash, ok := hashAlgorithms[algorithm]
+	if !ok {
+		return expectedSum{}, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+	digest = strings.ToLower(digest)
+	raw, err := hex.DecodeString(digest)
+	if err != nil || len(raw) != newHash().Size() {
+		return expectedSum{}, fmt.Errorf("invalid %s digest %q", algorithm, digest)
+	}
+	return expectedSum{algorithm: algorithm, digest: digest}, nil
+}
+
+// WriteSumsFile writes the digests of the files downloaded in this run to the --write-sums
+// manifest, with paths relative to the manifest so that it can be checked with sha256sum -c.
+func WriteSumsFile() error {
+	if WriteSums == "" {
+		return nil
+	}
+	manifest, err := expandTilde(WriteSums)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(manifest), 0755)
+	if err != nil {
+		return err
+	}
+	manifestDir, err := filepath.Abs(filepath.Dir(manifest))
+	if err != nil {
+		return err
+	}
+	sumsMu.Lock()
+	defer sumsMu.Unlock()
+	filePaths := make([]string, 0, len(writtenSums))
+	for filePath := range writtenSums {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+	var manifestContent strings.Builder
+	for _, filePath := range filePaths {
+		name := filePath
+		if absPath, err := filepath.Abs(filePath); err == nil {
+			if rel, err := filepath.Rel(manifestDir, absPath); err == nil {
+				name = rel
+			}
+		}
+		fmt.Fprintf(&manifestContent, "%s  %s\n", writtenSums[filePath], filepath.ToSlash(name))
+	}
+	return os.WriteFile(manifest, []byte(manifestContent.String()), 0644)
+}
+
+// checkChecksumOptions reports invalid checksum options before anything is downloaded.
+//
+// A --checksum digest only makes sense for a run downloading a single file, url, and not
+// with -i, --mirror or -p, which use --checksums instead.
+func checkChecksumOptions(url string, singleFile bool) error {
+	if Checksum != "" {
+		if !singleFile {
+			return fmt.Errorf("--checksum needs a single URL, use --checksums with -i, --mirror and -p")
+		}
+		checksumURL = url
+	}
+	if _, ok := hashAlgorithms[SumsAlgorithm]; !ok {
+		return fmt.Errorf("unsupported checksum algorithm %q", SumsAlgorithm)
+	}
+	if Checksum != "" || ChecksumsFile != "" {
+		return loadChecksums()
+	}
+	return nil
+}
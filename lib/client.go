@@ -0,0 +1,75 @@
+package wget
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	clientOnce sync.Once
+	client     *http.Client
+	clientErr  error
+)
+
+// getClient returns the HTTP client shared by every request of the run.
+//
+// Reusing a single client keeps connections alive between requests, so mirroring a site
+// or downloading segments from the same host does not pay a TCP and TLS handshake each time.
+func getClient() (*http.Client, error) {
+	clientOnce.Do(func() {
+		client, clientErr = newClient()
+	})
+	return client, clientErr
+}
+
+// newClient builds the HTTP client and its transport from the command line options.
+func newClient() (*http.Client, error) {
+	tlsConfig, err := getTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		Proxy:             proxyFromOptions(),
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: true,
+		MaxIdleConns:      MaxIdleConns,
+		// A mirror talks to a single host, so every idle connection may belong to it
+		MaxIdleConnsPerHost: MaxIdleConns,
+		MaxConnsPerHost:     MaxConnsPerHost,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	// A SOCKS5 proxy replaces the HTTP proxies, every connection goes through it
+	if Socks5 != "" {
+		transport.Proxy = nil
+		transport.DialContext, err = socks5DialContext()
+		if err != nil {
+			return nil, err
+		}
+	}
+	jar, err := newCookieJar()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Jar: jar, CheckRedirect: checkRedirect}, nil
+}
+
+// checkRedirect is called before following a redirect.
+//
+// It stops after MaxRedirect redirects and prints every hop. Credentials are dropped as
+// soon as the redirect leaves the host they were sent to, including for subdomains that
+// the Go client would otherwise trust.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > MaxRedirect {
+		return fmt.Errorf("%d redirections exceeded", MaxRedirect)
+	}
+	if req.Response != nil && board == nil {
+		fmt.Printf("Redirected: %s\nLocation: %s [following]\n", req.Response.Status, req.URL)
+	}
+	if req.URL.Host != via[len(via)-1].URL.Host {
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package wget
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// BenchmarkClientReuse compares the shared client of getClient with a new client per request
+// against a local TLS server, reporting the TLS handshakes each request costs.
+func BenchmarkClientReuse(b *testing.B) {
+	var handshakes int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&handshakes, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	// The test server has a self-signed certificate
+	savedCheckCertificate, savedNoProxy := CheckCertificate, NoProxy
+	b.Cleanup(func() {
+		CheckCertificate, NoProxy = savedCheckCertificate, savedNoProxy
+	})
+	CheckCertificate = false
+	NoProxy = true
+
+	get := func(b *testing.B, c *http.Client) {
+		resp, err := c.Get(server.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		c, err := getClient()
+		if err != nil {
+			b.Fatal(err)
+		}
+		atomic.StoreInt64(&handshakes, 0)
+		for i := 0; i < b.N; i++ {
+			get(b, c)
+		}
+		b.ReportMetric(float64(atomic.LoadInt64(&handshakes))/float64(b.N), "handshakes/op")
+	})
+
+	b.Run("per-request", func(b *testing.B) {
+		atomic.StoreInt64(&handshakes, 0)
+		for i := 0; i < b.N; i++ {
+			c, err := newClient()
+			if err != nil {
+				b.Fatal(err)
+			}
+			get(b, c)
+			c.CloseIdleConnections()
+		}
+		b.ReportMetric(float64(atomic.LoadInt64(&handshakes))/float64(b.N), "handshakes/op")
+	})
+}
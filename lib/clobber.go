@@ -0,0 +1,105 @@
+package wget
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// recursive is set while mirroring, where downloads overwrite the files of a previous mirror.
+var recursive bool
+
+var (
+	claimedMu sync.Mutex
+	// claimedPaths are the file names given to the downloads of this run, which may still
+	// be in progress in their .part file
+	claimedPaths = make(map[string]bool)
+	// backupPaths are the claimed files whose existing copy is rotated with --backups once
+	// their download completes
+	backupPaths = make(map[string]bool)
+)
+
+// existingFilePath decides where a new download of filePath is written when a file
+// of that name already exists. The second value is true when it must not be downloaded.
+//
+// With -nc the download is skipped. When the file is meant to be updated in place (--continue,
+// -N, --cache or mirroring) it is overwritten. With --backups=N the existing copies are
+// rotated to name.1 ... name.N when the download completes, so that a failed download keeps
+// the file in place. Otherwise the download is saved as name.1, name.2, ...
+//
+// Names are picked under a lock and claimed for the run, so that concurrent -i downloads
+// never share a file or its .part file.
+func existingFilePath(filePath string) (string, bool, error) {
+	claimedMu.Lock()
+	defer claimedMu.Unlock()
+	if !pathTaken(filePath) {
+		claimedPaths[filePath] = true
+		return filePath, false, nil
+	}
+	if NoClobber {
+		return filePath, true, nil
+	}
+	if Continue || Timestamping || UseCache || recursive {
+		return filePath, false, nil
+	}
+	// A name claimed by another download of the run is not there yet to be backed up
+	first := 1
+	if Backups > 0 {
+		if !claimedPaths[filePath] {
+			claimedPaths[filePath] = true
+			backupPaths[filePath] = true
+			return filePath, false, nil
+		}
+		// The backups of the other download will take name.1 ... name.N
+		first = Backups + 1
+	}
+	for i := first; ; i++ {
+		candidate := fmt.Sprintf("%s.%d", filePath, i)
+		if !pathTaken(candidate) {
+			claimedPaths[candidate] = true
+			return candidate, false, nil
+		}
+	}
+}
+
+// pathTaken reports whether a file name is used by a file, its .part file or another
+// download of the run.
+func pathTaken(filePath string) bool {
+	if claimedPaths[filePath] {
+		return true
+	}
+	for _, name := range []string{filePath, partPath(filePath)} {
+		if _, err := os.Lstat(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// backupBeforeCommit rotates the backups of a file about to be replaced by its download,
+// if existingFilePath asked for it.
+func backupBeforeCommit(filePath string) error {
+	claimedMu.Lock()
+	backup := backupPaths[filePath]
+	delete(backupPaths, filePath)
+	claimedMu.Unlock()
+	if _, err := os.Lstat(filePath); !backup || err != nil {
+		return nil
+	}
+	return rotateBackups(filePath, Backups)
+}
+
+// rotateBackups shifts name.1 ... name.(count-1) one number up, dropping name.count,
+// and moves the current file to name.1.
+func rotateBackups(filePath string, count int) error {
+	for i := count - 1; i >= 1; i-- {
+		older := fmt.Sprintf("%s.%d", filePath, i)
+		if _, err := os.Stat(older); err == nil {
+			err = os.Rename(older, fmt.Sprintf("%s.%d", filePath, i+1))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(filePath, filePath+".1")
+}
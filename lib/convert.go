@@ -0,0 +1,170 @@
+package wget
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// cssURLRegex matches the url() references and @import strings of a style sheet.
+var cssURLRegex = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)|@import\s+(['"])([^'"]+)(['"])`)
+
+// linkAttributes are the HTML attributes holding links that are converted with -k.
+var linkAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"poster":     true,
+	"background": true,
+}
+
+// convertLinks rewrites the links of the HTML and CSS files of the mirror for offline browsing.
+//
+// Links to resources that have a local copy become paths relative to the file, keeping their
+// fragment, and the other ones absolute URLs, keeping their query string and fragment.
+func convertLinks() {
+	resultsMu.Lock()
+	localFiles := make(map[string]string, len(savedPaths))
+	for link, filePath := range savedPaths {
+		localFiles[withoutFragment(link)] = filePath
+	}
+	resultsMu.Unlock()
+
+	converted := make(map[string]bool)
+	for pageURL, filePath := range localFiles {
+		if converted[filePath] {
+			continue
+		}
+		converted[filePath] = true
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			fmt.Printf("Error converting links in %s: %v\n", filePath, err)
+			continue
+		}
+		var newContent []byte
+		if strings.EqualFold(filepath.Ext(filePath), ".css") {
+			newContent = []byte(convertCSSLinks(string(content), pageURL, filePath, localFiles))
+		} else if strings.HasPrefix(http.DetectContentType(content), "text/html") {
+			newContent = convertHTMLLinks(content, pageURL, filePath, localFiles)
+		} else {
+			continue
+		}
+		if bytes.Equal(newContent, content) {
+			continue
+		}
+		err = os.WriteFile(partPath(filePath), newContent, 0644)
+		if err == nil {
+			err = os.Rename(partPath(filePath), filePath)
+		}
+		if err != nil {
+			fmt.Printf("Error converting links in %s: %v\n", filePath, err)
+			continue
+		}
+		fmt.Printf("Converted links in %s\n", filePath)
+	}
+}
+
+// convertHTMLLinks returns the content of an HTML page with its links, inline styles and
+// style elements converted. Tokens without links are copied unchanged.
+func convertHTMLLinks(content []byte, pageURL, filePath string, localFiles map[string]string) []byte {
+	var result bytes.Buffer
+	tokens := html.NewTokenizer(bytes.NewReader(content))
+	inStyle := false
+	for {
+		tokenType := tokens.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		// Raw is only valid until the token is parsed
+		raw := append([]byte(nil), tokens.Raw()...)
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokens.Token()
+			inStyle = token.Data == "style" && tokenType == html.StartTagToken
+			changed := false
+			for i, attr := range token.Attr {
+				newValue := attr.Val
+				if linkAttributes[attr.Key] {
+					newValue = convertLink(attr.Val, pageURL, filePath, localFiles)
+				} else if attr.Key == "style" {
+					newValue = convertCSSLinks(attr.Val, pageURL, filePath, localFiles)
+				}
+				if newValue != attr.Val {
+					token.Attr[i].Val = newValue
+					changed = true
+				}
+			}
+			if changed {
+				result.WriteString(token.String())
+				continue
+			}
+		case html.TextToken:
+			if inStyle {
+				result.WriteString(convertCSSLinks(string(raw), pageURL, filePath, localFiles))
+				continue
+			}
+		case html.EndTagToken:
+			inStyle = false
+		}
+		result.Write(raw)
+	}
+	return result.Bytes()
+}
+
+// convertCSSLinks returns a style sheet with its url() references and @import strings converted.
+func convertCSSLinks(css, pageURL, filePath string, localFiles map[string]string) string {
+	return cssURLRegex.ReplaceAllStringFunc(css, func(match string) string {
+		groups := cssURLRegex.FindStringSubmatch(match)
+		if groups[2] != "" {
+			return "url(" + groups[1] + convertLink(strings.TrimSpace(groups[2]), pageURL, filePath, localFiles) + groups[3] + ")"
+		}
+		return "@import " + groups[4] + convertLink(groups[5], pageURL, filePath, localFiles) + groups[6]
+	})
+}
+
+// convertLink returns the offline form of a link found in filePath, the local copy of pageURL.
+//
+// Links within the page, and those that are not http or https (mailto:, data:, javascript:),
+// are returned unchanged.
+func convertLink(link, pageURL, filePath string, localFiles map[string]string) string {
+	trimmed := strings.TrimSpace(link)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return link
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return link
+	}
+	ref, err := url.Parse(trimmed)
+	if err != nil {
+		return link
+	}
+	target := base.ResolveReference(ref)
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return link
+	}
+
+	localCopy, ok := localFiles[withoutFragment(target.String())]
+	if !ok {
+		return target.String()
+	}
+	rel, err := filepath.Rel(filepath.Dir(filePath), localCopy)
+	if err != nil {
+		return target.String()
+	}
+	// The query string is part of the local file name, so it is escaped along with the path
+	relURL := &url.URL{Path: filepath.ToSlash(rel), Fragment: target.Fragment}
+	return relURL.String()
+}
+
+// withoutFragment returns a URL without its #fragment.
+func withoutFragment(link string) string {
+	link, _, _ = strings.Cut(link, "#")
+	return link
+}
@@ -0,0 +1,284 @@
+package wget
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// cookieJar is the cookie store shared by every request of the run.
+//
+// Unlike net/http/cookiejar it can list all of its cookies, which saving them in the
+// Netscape cookies.txt format requires.
+type cookieJar struct {
+	mu      sync.Mutex
+	cookies []*jarCookie
+}
+
+// jarCookie is a cookie stored in the jar with the scope it applies to.
+type jarCookie struct {
+	name     string
+	value    string
+	domain   string
+	hostOnly bool
+	path     string
+	secure   bool
+	httpOnly bool
+	expires  time.Time // zero for a session cookie
+}
+
+// newCookieJar creates the jar, loading the --load-cookies file when given.
+func newCookieJar() (*cookieJar, error) {
+	jar := &cookieJar{}
+	if LoadCookies != "" {
+		err := jar.load(LoadCookies)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jar, nil
+}
+
+// SetCookies stores the cookies received in a response from u.
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+	for _, cookie := range cookies {
+		c := &jarCookie{
+			name:     cookie.Name,
+			value:    cookie.Value,
+			domain:   host,
+			hostOnly: true,
+			path:     cookie.Path,
+			secure:   cookie.Secure,
+			httpOnly: cookie.HttpOnly,
+		}
+		if cookie.Domain != "" {
+			domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+			if !domainMatch(host, domain) || isPublicSuffix(domain) {
+				continue
+			}
+			c.domain, c.hostOnly = domain, false
+		}
+		if c.path == "" || c.path[0] != '/' {
+			c.path = defaultCookiePath(u.Path)
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			c.expires = now.Add(-time.Second)
+		case cookie.MaxAge > 0:
+			c.expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			c.expires = cookie.Expires
+		}
+		j.store(c, now)
+	}
+}
+
+// Cookies returns the cookies to send in a request to u.
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	requestPath := u.Path
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	now := time.Now()
+	var cookies []*http.Cookie
+	for _, c := range j.cookies {
+		if c.expired(now) || c.secure && u.Scheme != "https" {
+			continue
+		}
+		if c.hostOnly && host != c.domain || !c.hostOnly && !domainMatch(host, c.domain) {
+			continue
+		}
+		if !pathMatch(requestPath, c.path) {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{Name: c.name, Value: c.value})
+	}
+	return cookies
+}
+
+// store replaces the cookie with the same name, domain and path, or adds it.
+// An expired cookie only removes the one it replaces.
+func (j *cookieJar) store(c *jarCookie, now time.Time) {
+	for i, existing := range j.cookies {
+		if existing.name == c.name && existing.domain == c.domain && existing.path == c.path {
+			if c.expired(now) {
+				j.cookies = append(j.cookies[:i], j.cookies[i+1:]...)
+			} else {
+				j.cookies[i] = c
+			}
+			return
+		}
+	}
+	if !c.expired(now) {
+		j.cookies = append(j.cookies, c)
+	}
+}
+
+// expired reports whether the cookie is past its expiry date. Session cookies never expire.
+func (c *jarCookie) expired(now time.Time) bool {
+	return !c.expires.IsZero() && !c.expires.After(now)
+}
+
+// load reads cookies from a file in the Netscape cookies.txt format used by browsers and curl.
+//
+// Each line holds the tab-separated domain, subdomain flag, path, secure flag, expiry as a Unix
+// time (0 for a session cookie), name and value. Lines starting with "#HttpOnly_" are cookies
+// restricted to HTTP, other lines starting with "#" are comments.
+func (j *cookieJar) load(fileName string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("error loading cookies: %v", err)
+	}
+	defer file.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		c := &jarCookie{
+			domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			hostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			path:     fields[2],
+			secure:   strings.EqualFold(fields[3], "TRUE"),
+			httpOnly: httpOnly,
+			name:     fields[5],
+			value:    fields[6],
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err == nil && expires > 0 {
+			c.expires = time.Unix(expires, 0)
+		}
+		j.store(c, now)
+	}
+	return scanner.Err()
+}
+
+// save writes the cookies to a file in the Netscape cookies.txt format.
+// Session cookies are only written when keepSession is set.
+func (j *cookieJar) save(fileName string, keepSession bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("error saving cookies: %v", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "# Netscape HTTP Cookie File")
+	fmt.Fprintln(w, "# Generated by wget. Edit at your own risk.")
+	fmt.Fprintln(w)
+	now := time.Now()
+	for _, c := range j.cookies {
+		if c.expired(now) || c.expires.IsZero() && !keepSession {
+			continue
+		}
+		domain := c.domain
+		if !c.hostOnly {
+			domain = "." + domain
+		}
+		if c.httpOnly {
+			domain = "#HttpOnly_" + domain
+		}
+		var expires int64
+		if !c.expires.IsZero() {
+			expires = c.expires.Unix()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain,
+			netscapeBool(!c.hostOnly),
+			c.path,
+			netscapeBool(c.secure),
+			expires,
+			c.name,
+			c.value,
+		)
+	}
+	return w.Flush()
+}
+
+// SaveCookies writes the cookies of the run to the --save-cookies file, if any.
+func SaveCookies() error {
+	if SaveCookiesFile == "" || client == nil {
+		return nil
+	}
+	jar, ok := client.Jar.(*cookieJar)
+	if !ok {
+		return nil
+	}
+	return jar.save(SaveCookiesFile, KeepSessionCookies)
+}
+
+// netscapeBool formats a flag of the cookies.txt format.
+func netscapeBool(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// domainMatch reports whether host is domain or one of its subdomains. IP addresses only match themselves.
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	return net.ParseIP(host) == nil && strings.HasSuffix(host, "."+domain)
+}
+
+// pathMatch reports whether the request path is within the cookie path.
+func pathMatch(requestPath, cookiePath string) bool {
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return len(requestPath) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath returns the directory of the request path, the default scope of a cookie.
+func defaultCookiePath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// isPublicSuffix reports whether a domain is a public suffix such as "com" or "co.uk",
+// on which a server may not set cookies.
+func isPublicSuffix(domain string) bool {
+	if net.ParseIP(domain) != nil {
+		return false
+	}
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
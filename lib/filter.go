@@ -0,0 +1,143 @@
+package wget
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// acceptRegexp and rejectRegexp are the compiled --accept-regex and --reject-regex.
+var acceptRegexp, rejectRegexp *regexp.Regexp
+
+// compileURLFilters compiles the --accept-regex and --reject-regex expressions.
+func compileURLFilters() error {
+	var err error
+	acceptRegexp, err = compileURLRegex(AcceptRegex)
+	if err != nil {
+		return err
+	}
+	rejectRegexp, err = compileURLRegex(RejectRegex)
+	return err
+}
+
+// compileURLRegex compiles an RE2 expression, case-insensitive with --ignore-case.
+// An empty expression gives nil.
+func compileURLRegex(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	if IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// urlRegexAllowed reports whether a full URL passes --accept-regex and --reject-regex.
+func urlRegexAllowed(link string) bool {
+	if rejectRegexp != nil && rejectRegexp.MatchString(link) {
+		return false
+	}
+	return acceptRegexp == nil || acceptRegexp.MatchString(link)
+}
+
+// nameAllowed reports whether the file name of a URL passes the -A accept and -R reject lists.
+//
+// Entries with wildcards (*.jpg, img-??.png) are matched against the whole name with
+// path.Match, the others are suffixes such as "jpg" or ".tar.gz". Directory URLs are matched
+// under fileName, the name they are saved as.
+func nameAllowed(link, fileName string, reject []string) bool {
+	name := fileName
+	if u, err := url.Parse(link); err == nil && u.Path != "" && !strings.HasSuffix(u.Path, "/") {
+		name = path.Base(u.Path)
+	}
+	if matchNames(name, reject) {
+		return false
+	}
+	return !hasEntries(AcceptList) || matchNames(name, AcceptList)
+}
+
+// matchNames reports whether a file name matches an entry of an -A or -R list.
+func matchNames(name string, patterns []string) bool {
+	if IgnoreCase {
+		name = strings.ToLower(name)
+	}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if IgnoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		if strings.ContainsAny(pattern, "*?[") {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		} else if strings.HasSuffix(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// directoryAllowed reports whether the directory of a URL passes the -I and -X lists.
+//
+// A list entry matches its directory and everything below it. Entries may hold the
+// wildcards of path.Match, which then apply to the directory or any of its parents.
+func directoryAllowed(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return true
+	}
+	dir := path.Clean("/" + u.Path)
+	if !strings.HasSuffix(u.Path, "/") {
+		dir = path.Dir(dir)
+	}
+	if hasEntries(IncludeDirectories) && !matchDirectories(dir, IncludeDirectories) {
+		return false
+	}
+	return !matchDirectories(dir, ExcludeDirectories)
+}
+
+// matchDirectories reports whether dir is one of the directories of a list, or below one.
+func matchDirectories(dir string, directories []string) bool {
+	if IgnoreCase {
+		dir = strings.ToLower(dir)
+	}
+	for _, pattern := range directories {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if IgnoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		pattern = path.Clean("/" + pattern)
+		if !strings.ContainsAny(pattern, "*?[") {
+			if dir == pattern || pattern == "/" || strings.HasPrefix(dir, pattern+"/") {
+				return true
+			}
+			continue
+		}
+		for parent := dir; ; parent = path.Dir(parent) {
+			if matched, _ := path.Match(pattern, parent); matched {
+				return true
+			}
+			if parent == "/" {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// hasEntries reports whether a comma-separated flag list holds anything.
+func hasEntries(list []string) bool {
+	for _, entry := range list {
+		if strings.TrimSpace(entry) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -1,17 +1,19 @@
 package wget
 
 import (
-	"crypto/tls"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/html"
@@ -22,13 +24,52 @@ var Res []int
 var Finish = ""
 var TabUrl []string
 
+// resultsMu guards Res, Finish and TabUrl while -i downloads run concurrently.
+var resultsMu sync.Mutex
+
 const user_agent = "Golang Mirror v. 2.0"
 
+// maxDepth is how many links away from the start page the crawl goes, -1 for no limit.
+var maxDepth = -1
+
 // MirrorWebsite mirrors a website by recursively downloading all its pages.
 //
 // It takes a URL and an output directory as parameters and returns an error if the operation fails.
 func MirrorWebsite(urlString, downloadPath string, reject []string, logFile bool, rateLimit int) error {
+	maxDepth = -1
+	if Level > 0 {
+		maxDepth = Level
+	}
+	err := crawl(urlString, reject, make(map[string]int), logFile, rateLimit)
+	if ConvertLinks {
+		convertLinks()
+	}
+	return err
+}
+
+// DownloadPages downloads pages with their page requisites, without following their links (-p).
+// Requisites shared by several pages are only downloaded once, and -k converts every page at the end.
+func DownloadPages(urls []string, downloadPath string, reject []string, logFile bool, rateLimit int) error {
+	maxDepth = 0
+	PageRequisites = true
+	visited := make(map[string]int)
+	var errs []error
+	for _, urlString := range urls {
+		err := crawl(urlString, reject, visited, logFile, rateLimit)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if ConvertLinks {
+		convertLinks()
+	}
+	return errors.Join(errs...)
+}
+
+// crawl saves the pages reachable from urlString within maxDepth in the folder of its domain.
+func crawl(urlString string, reject []string, visited map[string]int, logFile bool, rateLimit int) error {
 	Domain = GetDomain(urlString)
+	recursive = true
 	folderName := filepath.Join(".", Domain)
 
 	// Create output directory
@@ -36,8 +77,7 @@ func MirrorWebsite(urlString, downloadPath string, reject []string, logFile bool
 	if err != nil {
 		return fmt.Errorf("error creating output directory: %v", err)
 	}
-	visited := make(map[string]bool)
-	return mirrorPage(urlString, folderName, reject, visited, logFile, rateLimit)
+	return mirrorPage(urlString, folderName, reject, visited, 0, logFile, rateLimit)
 }
 
 // mirrorPage mirrors a web page by downloading its resources and recursively mirroring linked pages.
@@ -45,25 +85,88 @@ func MirrorWebsite(urlString, downloadPath string, reject []string, logFile bool
 // Parameters:
 // - url: the URL of the web page to mirror.
 // - outputDir: the directory where the mirrored resources will be saved.
-// - visited: a map that keeps track of visited URLs to avoid duplicates.
+// - visited: the depth at which every visited URL was crawled, to avoid duplicates.
+// - depth: the number of links between the start page and this one.
 //
 // Returns:
 // - error: an error if there was a problem while mirroring the page, otherwise nil.
-func mirrorPage(url, outputDir string, reject []string, visited map[string]bool, logFile bool, rateLimit int) error {
-	if GetDomain(url) != Domain || visited[url] {
+func mirrorPage(url, outputDir string, reject []string, visited map[string]int, depth int, logFile bool, rateLimit int) error {
+	// A page is crawled again when reached through a shorter path, as its links may then be in reach
+	if previous, ok := visited[url]; GetDomain(url) != Domain || (ok && previous <= depth) {
+		return nil
+	}
+	visited[url] = depth
+	if !directoryAllowed(url) {
+		if Verbose {
+			fmt.Printf("Excluding %s: directory not allowed by -X/-I\n", url)
+		}
 		return nil
 	}
+	if !urlRegexAllowed(url) {
+		if Verbose {
+			fmt.Printf("Rejecting %s: not allowed by the URL regular expressions\n", url)
+		}
+		return nil
+	}
+	if !robotsAllowed(url) {
+		if Verbose {
+			fmt.Printf("Skipping %s: disallowed by robots.txt\n", url)
+		}
+		return nil
+	}
+	followLinks := maxDepth < 0 || depth < maxDepth
 
-	visited[url] = true
+	fileName, _ := GetFilenameAndDirFromURL(url)
+	localPath := path.Join(outputDir, fileName)
 
-	resp, err := launchRequest(url)
-	if err != nil {
-		return err
+	// With -N or --cache, a page that did not change on the server is parsed from its local copy,
+	// and notice tells why it is not downloaded again
+	var body io.Reader
+	baseURL := url
+	localCopy, notice := "", ""
+	if info, err := os.Stat(localPath); Timestamping && err == nil {
+		if _, upToDate := isUpToDate(url, info); upToDate {
+			localCopy = localPath
+			notice = fmt.Sprintf("Server file no newer than local file ‘%s’ -- not retrieving.\n", localPath)
+		}
+	}
+	if entry, ok := cache.fresh(url); ok && localCopy == "" {
+		localCopy = entry.FilePath
+		notice = fmt.Sprintf("Cached copy ‘%s’ is fresh -- not retrieving.\n", entry.FilePath)
+	}
+	if localCopy != "" {
+		body = openLocalCopy(localCopy)
 	}
-	defer resp.Body.Close()
 
-	tokens := html.NewTokenizer(resp.Body)
+	if body == nil {
+		resp, err := launchRequestWithRetry(url, 0)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if entry, ok := cache.lookup(url); ok && resp.StatusCode == http.StatusNotModified {
+			cache.refresh(url, resp)
+			localCopy = entry.FilePath
+			notice = fmt.Sprintf("Server file not modified -- keeping cached copy of %s.\n", url)
+			resp.Body = openLocalCopy(entry.FilePath)
+		}
 
+		// Links are relative to the page a redirect led to, which must stay on the mirrored domain
+		baseURL = resp.Request.URL.String()
+		if baseURL != url {
+			if GetDomain(baseURL) != Domain {
+				fmt.Printf("Skipping %s: redirected off-domain to %s\n", url, baseURL)
+				return nil
+			}
+			visited[baseURL] = depth
+		}
+		body = resp.Body
+	}
+
+	tokens := html.NewTokenizer(body)
+
+	// Set by <meta name="robots"> tags
+	nofollow, noindex := false, false
 	stop := false
 	for {
 		tokenType := tokens.Next()
@@ -72,12 +175,31 @@ func mirrorPage(url, outputDir string, reject []string, visited map[string]bool,
 			stop = true // Finished parsing
 		case html.StartTagToken, html.SelfClosingTagToken:
 			token := tokens.Token()
-			if token.Data == "a" || token.Data == "link" || token.Data == "img" || token.Data == "script" {
+			if token.Data == "meta" && Robots && strings.EqualFold(attrValue(token, "name"), "robots") {
+				metaNofollow, metaNoindex := metaRobots(attrValue(token, "content"))
+				nofollow = nofollow || metaNofollow
+				noindex = noindex || metaNoindex
+			}
+			if crawledTags[token.Data] {
 				for _, attr := range token.Attr {
-					if attr.Key == "href" || attr.Key == "src" {
+					if linkAttributes[attr.Key] {
 						link := attr.Val
 						if !strings.HasPrefix(link, "http") {
-							link = resolveRelativeURL(url, link)
+							link = resolveRelativeURL(baseURL, link)
+						}
+						// A fragment points within a page, which is saved only once
+						link = withoutFragment(link)
+						if link == url || link == baseURL {
+							continue
+						}
+
+						// Beyond the depth limit, only the page requisites are fetched with -p
+						if !followLinks && !(PageRequisites && isPageRequisite(token)) {
+							continue
+						}
+						// Links marked nofollow are not crawled, the page still gets its requisites
+						if Robots && (nofollow || hasRel(token, "nofollow")) && !isPageRequisite(token) {
+							continue
 						}
 
 						// Download and save the linked resource
@@ -92,7 +214,9 @@ func mirrorPage(url, outputDir string, reject []string, visited map[string]bool,
 						}
 
 						// Recursively mirror linked page
-						mirrorPage(link, outputDir, reject, visited, logFile, rateLimit)
+						if followLinks {
+							mirrorPage(link, outputDir, reject, visited, depth+1, logFile, rateLimit)
+						}
 					}
 				}
 			}
@@ -104,14 +228,163 @@ func mirrorPage(url, outputDir string, reject []string, visited map[string]bool,
 		}
 	}
 
-	fileName, _ := GetFilenameAndDirFromURL(url)
-	_, err, _, _, _ = DownloadAndSaveResource(url, fileName, outputDir, reject, logFile, rateLimit, false)
+	// A noindex page was only needed for its links
+	if noindex {
+		if Verbose {
+			fmt.Printf("Not saving %s: noindex in its robots meta tag\n", url)
+		}
+		return nil
+	}
+
+	// Pages rejected by -A/-R are still parsed for links, they are only not kept.
+	// A copy of an earlier run is removed.
+	if !nameAllowed(url, fileName, reject) {
+		if os.Remove(localPath) == nil {
+			fmt.Printf("Removing %s since it should be rejected.\n", localPath)
+		} else if Verbose {
+			fmt.Printf("Rejecting %s: not allowed by -A/-R, its links were followed\n", url)
+		}
+		return nil
+	}
+
+	// The page was checked with the server already, asking again would only repeat the request
+	if localCopy != "" {
+		if !logFile {
+			fmt.Print(notice)
+		}
+		recordSavedPath(url, localCopy)
+		if err := verifyExistingFile(url, fileName, localCopy); err != nil {
+			fmt.Printf("Error downloading %s: %v\n", url, err)
+		}
+		return nil
+	}
+	_, err, _, _, _ := DownloadAndSaveResource(url, fileName, outputDir, reject, logFile, rateLimit, false)
 	if err != nil {
 		fmt.Printf("Error downloading %s: %v\n", url, err)
 	}
 	return nil
 }
 
+// crawledTags are the HTML elements whose links are followed by the crawl.
+var crawledTags = map[string]bool{
+	"a":      true,
+	"link":   true,
+	"img":    true,
+	"script": true,
+	"source": true,
+	"video":  true,
+	"audio":  true,
+	"track":  true,
+	"embed":  true,
+	"input":  true,
+	"body":   true,
+}
+
+// attrValue returns the value of an attribute of an HTML element, or "" if it has none.
+func attrValue(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasRel reports whether the rel attribute of an element holds a link type.
+func hasRel(token html.Token, linkType string) bool {
+	for _, rel := range strings.Fields(attrValue(token, "rel")) {
+		if strings.EqualFold(rel, linkType) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPageRequisite reports whether the links of an element are needed to render the page:
+// images, scripts, media, and style sheets, icons and preloaded fonts of link elements.
+func isPageRequisite(token html.Token) bool {
+	if token.Data != "link" {
+		return token.Data != "a"
+	}
+	for _, attr := range token.Attr {
+		if attr.Key != "rel" {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.ToLower(attr.Val)) {
+			switch rel {
+			case "stylesheet", "icon", "apple-touch-icon", "preload", "modulepreload", "manifest":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// partPath returns the temporary file a download of filePath is written to until it completes.
+func partPath(filePath string) string {
+	return filePath + ".part"
+}
+
+// partialSize returns the size of the partial download of filePath to resume with --continue,
+// and the file holding it: its .part file, or else a file left at the real name, for instance
+// by another program. The latter is only copied to the .part file once the server agrees to
+// resume, so that it stays complete if the download fails.
+func partialSize(filePath string) (int, string) {
+	for _, partial := range []string{partPath(filePath), filePath} {
+		if info, err := os.Stat(partial); err == nil && info.Mode().IsRegular() {
+			return int(info.Size()), partial
+		}
+	}
+	return 0, ""
+}
+
+// copyFile copies the content of a file to another one, replacing it.
+func copyFile(source, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// commitPartFile flushes a completed .part file to disk and renames it to its real name,
+// so that a file at the real name is always complete. The file it replaces is backed up
+// first with --backups.
+func commitPartFile(localFile *os.File, tempPath, filePath string) error {
+	err := localFile.Sync()
+	if err != nil {
+		return err
+	}
+	err = localFile.Close()
+	if err != nil {
+		return err
+	}
+	err = backupBeforeCommit(filePath)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempPath, filePath)
+}
+
+// openLocalCopy returns the content of a previously downloaded file to parse it again,
+// or an empty body when it cannot be read.
+func openLocalCopy(filePath string) io.ReadCloser {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return io.NopCloser(strings.NewReader(""))
+	}
+	return io.NopCloser(bytes.NewReader(data))
+}
+
 func GetFilenameAndDirFromURL(link string) (string, string) {
 	_subDir, fileName := path.Split(link)
 	if fileName == "" {
@@ -165,147 +438,350 @@ func GetDomain(urlString string) string {
 // Returns:
 // - error: an error if any occurred during the download or saving process
 func DownloadAndSaveResource(url, fileName, outputDir string, reject []string, logFile bool, rateLimit int, changeDisplay bool) (*http.Response, error, []int, string, []string) {
-	for _, ext := range reject {
-		if ext != "" && strings.HasSuffix(fileName, ext) {
-			return nil, nil, nil, "", nil
-		}
-	}
-
-	if GetDomain(url) != Domain {
-		return nil, fmt.Errorf("domain mismatch: %s != %s", GetDomain(url), Domain), nil, "", nil
-	}
-	resp, err := launchRequest(url)
+	resp, _, err := downloadResource(url, fileName, outputDir, reject, logFile, rateLimit, changeDisplay)
 	if err != nil {
 		return resp, err, nil, "", nil
 	}
-	defer resp.Body.Close()
+	res, finish, tabUrl := collectedResults()
+	return resp, nil, res, finish, tabUrl
+}
 
-	totalSize, err := strconv.Atoi(resp.Header.Get("Content-Length"))
-	if err != nil {
-		buffer := make([]byte, 1024)
-		for {
-			n, err := resp.Body.Read(buffer)
-			if err == io.EOF {
-				totalSize += n
-				break
-			}
-			if err != nil {
-				return resp, err, nil, "", nil
-			}
-			totalSize += n
+// downloadResource downloads a resource like DownloadAndSaveResource, also reporting whether
+// it was skipped: left out by the filters, already there with -nc, up to date with -N or -c,
+// or kept from the cache.
+func downloadResource(url, fileName, outputDir string, reject []string, logFile bool, rateLimit int, changeDisplay bool) (*http.Response, bool, error) {
+	// A crawl saves every URL once, however many pages refer to it
+	if _, saved := savedPath(url); recursive && saved {
+		return nil, true, nil
+	}
+	if !urlRegexAllowed(url) || !nameAllowed(url, fileName, reject) {
+		if Verbose && !changeDisplay {
+			fmt.Printf("Rejecting %s: not allowed by -A/-R or the URL regular expressions\n", url)
 		}
-		resp, err = launchRequest(url)
-		if err != nil {
-			return resp, err, nil, "", nil
+		return nil, true, nil
+	}
+	if !directoryAllowed(url) {
+		if Verbose && !changeDisplay {
+			fmt.Printf("Excluding %s: directory not allowed by -X/-I\n", url)
 		}
-		defer resp.Body.Close()
-		// return resp, fmt.Errorf("error converting total size: %s", err)
+		return nil, true, nil
+	}
+	if !robotsAllowed(url) {
+		if Verbose && !changeDisplay {
+			fmt.Printf("Skipping %s: disallowed by robots.txt\n", url)
+		}
+		return nil, true, nil
+	}
+
+	if Domain != "" && GetDomain(url) != Domain {
+		return nil, false, fmt.Errorf("domain mismatch: %s != %s", GetDomain(url), Domain)
 	}
 
 	// Create the directory structure if it doesn't exist
-	outputDir, err = expandTilde(outputDir)
+	outputDir, err := expandTilde(outputDir)
 	if err != nil {
-		return resp, err, nil, "", nil
+		return nil, false, err
 	}
 	_, err = os.Stat(outputDir)
 	if os.IsNotExist(err) {
 		// The folder does not exist.
 		err = os.MkdirAll(outputDir, os.ModePerm)
 		if err != nil {
-			return resp, err, nil, "", nil
+			return nil, false, err
 		}
 	}
+	filePath := path.Join(outputDir, fileName)
+
+	// With -nc, existing files are never downloaded again
+	if _, err := os.Stat(filePath); NoClobber && err == nil {
+		if !logFile && !changeDisplay {
+			fmt.Printf("File ‘%s’ already there; not retrieving.\n", filePath)
+		}
+		recordSavedPath(url, filePath)
+		return nil, true, verifyExistingFile(url, fileName, filePath)
+	}
+
+	// With --continue, pick up from the end of an existing partial file
+	offset, partial := 0, ""
+	if Continue {
+		offset, partial = partialSize(filePath)
+	}
+
+	// With -N, files that did not change on the server since they were downloaded are skipped
+	if Timestamping {
+		if info, err := os.Stat(filePath); err == nil {
+			if head, upToDate := isUpToDate(url, info); upToDate {
+				if !logFile && !changeDisplay {
+					fmt.Printf("Server file no newer than local file ‘%s’ -- not retrieving.\n", filePath)
+				}
+				recordSavedPath(url, filePath)
+				return head, true, verifyExistingFile(url, fileName, filePath)
+			}
+		}
+	}
+
+	// With --cache, a fresh copy is used without contacting the server
+	if entry, ok := cache.fresh(url); ok {
+		if !logFile && !changeDisplay {
+			fmt.Printf("Cached copy ‘%s’ is fresh -- not retrieving.\n", entry.FilePath)
+		}
+		recordSavedPath(url, entry.FilePath)
+		return nil, true, verifyExistingFile(url, fileName, entry.FilePath)
+	}
+
+	// With --segments, large files are fetched over several connections at once
+	if Segments > 1 && offset == 0 && Method == http.MethodGet {
+		probe, size, err := probeRanges(url)
+		if err == nil && size >= 2*minSegmentSize {
+			fileName = responseFileName(probe, url, fileName)
+			filePath, skip, err := existingFilePath(path.Join(outputDir, fileName))
+			if skip && !logFile && !changeDisplay {
+				fmt.Printf("File ‘%s’ already there; not retrieving.\n", filePath)
+			}
+			if skip {
+				recordSavedPath(url, filePath)
+				err = verifyExistingFile(url, fileName, filePath)
+			}
+			if err != nil || skip {
+				return probe, skip, err
+			}
+			err = downloadSegmented(probe, url, fileName, filePath, size, logFile, rateLimit, changeDisplay)
+			if err != nil {
+				return probe, false, err
+			}
+			return probe, false, nil
+		}
+	}
+
+	resp, err := launchRequestWithRetry(url, offset)
+	if err != nil {
+		return resp, false, err
+	}
+	defer resp.Body.Close()
+
+	rangeTotal := -1
+	if offset > 0 {
+		switch resp.StatusCode {
+		case http.StatusPartialContent:
+			start, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+			if err != nil {
+				return resp, false, err
+			}
+			if start != offset {
+				return resp, false, fmt.Errorf("server resumed at byte %d instead of %d", start, offset)
+			}
+			rangeTotal = total
+			if partial == filePath {
+				err = copyFile(filePath, partPath(filePath))
+				if err != nil {
+					return resp, false, err
+				}
+			}
+		case http.StatusRequestedRangeNotSatisfiable:
+			if !logFile && !changeDisplay {
+				fmt.Printf("The file %s is already fully retrieved; nothing to do.\n", filePath)
+			}
+			if partial != filePath {
+				err = os.Rename(partial, filePath)
+			}
+			if err == nil {
+				err = verifyExistingFile(url, fileName, filePath)
+			}
+			recordSavedPath(url, filePath)
+			return resp, true, err
+		default:
+			// The server ignored the range, start again from the beginning
+			offset = 0
+		}
+	}
+
+	// The cached copy was revalidated by the server
+	if resp.StatusCode == http.StatusNotModified {
+		cache.refresh(url, resp)
+		if !logFile && !changeDisplay {
+			fmt.Printf("Server file not modified -- keeping cached copy of %s.\n", url)
+		}
+		if entry, ok := cache.lookup(url); ok {
+			recordSavedPath(url, entry.FilePath)
+			return resp, true, verifyExistingFile(url, fileName, entry.FilePath)
+		}
+		return resp, true, nil
+	}
+
+	// A redirect must not leave the mirrored domain
+	finalURL := resp.Request.URL.String()
+	if Domain != "" && GetDomain(finalURL) != Domain {
+		return resp, false, fmt.Errorf("redirected off-domain to %s", finalURL)
+	}
+	if offset == 0 {
+		fileName = responseFileName(resp, url, fileName)
+		var skip bool
+		filePath, skip, err = existingFilePath(path.Join(outputDir, fileName))
+		if skip && !logFile && !changeDisplay {
+			fmt.Printf("File ‘%s’ already there; not retrieving.\n", filePath)
+		}
+		if skip {
+			recordSavedPath(url, filePath)
+			err = verifyExistingFile(url, fileName, filePath)
+		}
+		if err != nil || skip {
+			return resp, skip, err
+		}
+	}
+
+	// A negative size means the server did not announce it (e.g. chunked encoding),
+	// the body is then streamed until the connection reports the end of it
+	totalSize := int(resp.ContentLength)
+	if rangeTotal >= 0 {
+		totalSize = rangeTotal
+	} else if totalSize >= 0 {
+		totalSize += offset
+	}
 
-	const barWidth = 50
-	progress := make([]rune, barWidth)
 	startTime := time.Now()
 	startTimeString := startTime.Format("2006-01-02 15:04:05")
 	initString := ""
-	endString := ""
 	initString += fmt.Sprintf("Start at: %s\n", startTimeString)
 	initString += "Sending request, awaiting response... "
-	if resp.StatusCode == http.StatusOK {
-		initString += "status 200 OK\n"
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent {
+		initString += fmt.Sprintf("status %s\n", resp.Status)
 	} else {
-		return resp, fmt.Errorf("error %s", err), nil, "", nil
+		return resp, false, fmt.Errorf("server returned %s", resp.Status)
+	}
+	if totalSize >= 0 {
+		initString += fmt.Sprintf("Content size: %s\n", FormatFileSize(totalSize))
+	} else {
+		initString += "Content size: unspecified\n"
+	}
+	if offset > 0 && totalSize >= 0 {
+		initString += fmt.Sprintf("Resuming at: %s (%s remaining)\n", FormatFileSize(offset), FormatFileSize(totalSize-offset))
 	}
-	initString += fmt.Sprintf("Content size: %s\n", FormatFileSize(totalSize))
 
-	// Create the local file and copy the resource into it
-	filePath := path.Join(outputDir, fileName)
-	localFile, err := os.Create(filePath)
+	// Download into a temporary .part file, appending when resuming, which only takes
+	// the real name once complete. It is kept for --continue if the download fails.
+	tempPath := partPath(filePath)
+	completed := false
+	defer func() {
+		if !completed && !Continue {
+			os.Remove(tempPath)
+		}
+	}()
+	var localFile *os.File
+	if offset > 0 {
+		localFile, err = os.OpenFile(tempPath, os.O_WRONLY|os.O_APPEND, 0644)
+	} else {
+		localFile, err = os.Create(tempPath)
+	}
 	if err != nil {
-		return resp, err, nil, "", nil
+		return resp, false, err
 	}
 	defer localFile.Close()
+	hasher, err := newFileHasher()
+	if err == nil && offset > 0 {
+		err = hasher.hashFile(tempPath, offset)
+	}
+	if err != nil {
+		return resp, false, err
+	}
+	writer := io.MultiWriter(localFile, hasher)
 	initString += fmt.Sprintf("Saving file to: %s\n", filePath)
 
 	if !logFile && !changeDisplay {
 		fmt.Print(initString)
 	}
-	if changeDisplay {
-		Res = append(Res, totalSize)
-	}
-
-	var downloadedSize int
-	var file *os.File
+	downloadedSize := offset
+	failures := 0
 	for {
 		buffer := make([]byte, 1024)
-		chunk, err := resp.Body.Read(buffer)
-		if err != nil && err != io.EOF {
-			return resp, fmt.Errorf("error %s", err), nil, "", nil
-		}
+		chunk, readErr := resp.Body.Read(buffer)
 
-		_, err = localFile.Write(buffer[:chunk])
+		_, err = writer.Write(buffer[:chunk])
 		if err != nil {
-			return resp, fmt.Errorf("error %s", err), nil, "", nil
+			return resp, false, fmt.Errorf("error %s", err)
 		}
 
 		downloadedSize += chunk
 
-		progressLength := int(float64(downloadedSize) / float64(totalSize) * barWidth)
-		for i := 0; i < barWidth; i++ {
-			if i < progressLength {
-				progress[i] = '='
-			} else {
-				progress[i] = ' '
+		// The connection dropped mid-body: resume from what we already have
+		if readErr != nil && readErr != io.EOF {
+			failures++
+			if !isRetryableError(readErr) || !shouldRetry(failures) {
+				return resp, false, fmt.Errorf("error %s", readErr)
+			}
+			resp.Body.Close()
+			wait := retryDelay(failures)
+			if !logFile && !changeDisplay {
+				fmt.Printf("\nConnection lost at %s (%v), resuming in %s...\n", FormatFileSize(downloadedSize), readErr, wait.Truncate(time.Millisecond))
+			}
+			time.Sleep(wait)
+			resp, err = launchRequestWithRetry(url, downloadedSize)
+			if err != nil {
+				return resp, false, err
 			}
+			defer resp.Body.Close()
+			switch resp.StatusCode {
+			case http.StatusPartialContent:
+				start, _, err := parseContentRange(resp.Header.Get("Content-Range"))
+				if err != nil {
+					return resp, false, err
+				}
+				if start != downloadedSize {
+					return resp, false, fmt.Errorf("server resumed at byte %d instead of %d", start, downloadedSize)
+				}
+			case http.StatusOK:
+				// No range support, start the file over
+				if err := localFile.Truncate(0); err != nil {
+					return resp, false, err
+				}
+				if _, err := localFile.Seek(0, io.SeekStart); err != nil {
+					return resp, false, err
+				}
+				hasher.Reset()
+				downloadedSize, offset = 0, 0
+				startTime = time.Now()
+			default:
+				return resp, false, fmt.Errorf("server returned %s", resp.Status)
+			}
+			continue
 		}
 
-		elapsedTime := time.Since(startTime)
-
-		bytesPerSec := int(float64(downloadedSize) / elapsedTime.Seconds())
-		remainingTime := time.Duration(float64(elapsedTime) / float64(downloadedSize) * float64(totalSize-downloadedSize))
-
+		sessionSize := downloadedSize - offset
+		bytesPerSec := int(float64(sessionSize) / time.Since(startTime).Seconds())
 		if !logFile && !changeDisplay {
-			fmt.Printf(
-				"\r %s / %s [%s] %.2f%% - %s/s Time Remaining: %s - Time Elapsed: %s",
-				FormatFileSize(downloadedSize),
-				FormatFileSize(totalSize),
-				string(progress),
-				float64(downloadedSize)/float64(totalSize)*100,
-				FormatFileSize(bytesPerSec),
-				remainingTime.Truncate(time.Second).String(),
-				elapsedTime.Truncate(time.Second).String(),
-			)
-		}
-
-		if err == io.EOF || (downloadedSize == totalSize) {
-			endTime := time.Now()
-			endTimeString := endTime.Format("2006-01-02 15:04:05")
-			endString += fmt.Sprintf("Download completed [%s]\n", url)
-			endString += fmt.Sprintf("finished at: %s\n", endTimeString)
-			if !logFile && !changeDisplay {
-				fmt.Print("\n\n" + endString)
-			} else if logFile {
-				file, err = os.Create("wget-log")
+			if totalSize >= 0 {
+				printProgress(downloadedSize, totalSize, sessionSize, startTime)
+			} else {
+				printStreamProgress(downloadedSize, sessionSize, startTime)
+			}
+		} else if changeDisplay {
+			board.update(url, fileName, downloadedSize, totalSize)
+		}
+
+		if readErr == io.EOF || (downloadedSize == totalSize) {
+			if totalSize >= 0 && downloadedSize != totalSize {
+				return resp, false, fmt.Errorf("download incomplete: got %d of %d bytes", downloadedSize, totalSize)
+			}
+			err = verifyChecksum(hasher, url, fileName, tempPath, filePath)
+			if err != nil {
+				return resp, false, err
+			}
+			err = commitPartFile(localFile, tempPath, filePath)
+			if err != nil {
+				return resp, false, err
+			}
+			completed = true
+			recordSum(hasher, filePath)
+			if Timestamping {
+				err = setModTime(filePath, resp)
 				if err != nil {
-					return resp, fmt.Errorf("error %s", err), nil, "", nil
+					return resp, false, err
 				}
-				file.WriteString(initString + endString)
-			} else if changeDisplay {
-				Finish += "finished " + fileName + "\n"
-				TabUrl = append(TabUrl, url)
+			}
+			cache.store(url, filePath, resp, downloadedSize)
+			recordSavedPath(url, filePath)
+			err = reportCompletion(url, fileName, initString, downloadedSize, totalSize < 0, logFile, changeDisplay)
+			if err != nil {
+				return resp, false, err
 			}
 			break
 		}
@@ -316,50 +792,307 @@ func DownloadAndSaveResource(url, fileName, outputDir string, reject []string, l
 		}
 	}
 
-	// if strings.HasSuffix(fileName, ".css") {
+	// The files referenced by a style sheet are page requisites of the crawl
+	if recursive && isStyleSheet(resp) {
 		cssContent, err := os.ReadFile(filePath)
 		if err != nil {
 			fmt.Println("Error reading CSS file:", err)
-			return resp, fmt.Errorf("error %s", err), nil, "", nil
+			return resp, false, fmt.Errorf("error %s", err)
 		}
 
-		// Define a regular expression to match URLs in the CSS file
-		urlRegex := regexp.MustCompile(`url\(['"]?([^'"]+)'["']?\)`)
-
-		// Find all matches in the CSS content
-		matches := urlRegex.FindAllStringSubmatch(string(cssContent), -1)
+		// Find all url() references and @import strings in the CSS content
+		matches := cssURLRegex.FindAllStringSubmatch(string(cssContent), -1)
 
-		// Extract and print the URLs
+		// Download the referenced files, which are relative to the style sheet
 		for _, match := range matches {
-			if len(match) > 1 {
-				url := match[1]
+			url := strings.TrimSpace(match[2] + match[5])
+			url = resolveRelativeURL(finalURL, url)
+			if strings.HasPrefix(url, "http") {
 				fileName, outputDir := GetFilenameAndDirFromURL(url)
 				DownloadAndSaveResource(url, fileName, outputDir, reject, logFile, rateLimit, changeDisplay)
 			}
 		}
-	// }
+	}
+
+	return resp, false, err
+}
+
+// isStyleSheet reports whether a response is a CSS style sheet, by its Content-Type or
+// the extension of its URL.
+func isStyleSheet(resp *http.Response) bool {
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == "text/css" {
+		return true
+	}
+	return strings.EqualFold(path.Ext(resp.Request.URL.Path), ".css")
+}
+
+// outputName is the file name given with -O, which the server never overrides.
+var outputName string
+
+// responseFileName returns the name to save a response under.
+//
+// With --content-disposition, the name comes from the Content-Disposition header, and with
+// --trust-server-names from the URL a redirect led to. Otherwise, and always with -O,
+// fileName is kept.
+func responseFileName(resp *http.Response, url, fileName string) string {
+	if outputName != "" {
+		return fileName
+	}
+	if ContentDisposition {
+		if name := contentDispositionFileName(resp.Header.Get("Content-Disposition")); name != "" {
+			return name
+		}
+	}
+	if finalURL := resp.Request.URL.String(); TrustServerNames && finalURL != url {
+		name, err := getResourceName(finalURL)
+		if err == nil && name != "/" && name != "." {
+			return name
+		}
+	}
+	return fileName
+}
+
+// contentDispositionFileName returns the file name of a Content-Disposition header, or "" if it has none.
+//
+// The RFC 6266 "filename*" parameter, which may be UTF-8 encoded, takes precedence over
+// "filename". The name is reduced to its last path element, so that a server cannot write
+// outside the download directory.
+func contentDispositionFileName(contentDisposition string) string {
+	if contentDisposition == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentDisposition)
+	if err != nil {
+		return ""
+	}
+	// ParseMediaType decodes "filename*" into "filename"
+	name := strings.ReplaceAll(params["filename"], "\\", "/")
+	name = path.Base(name)
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(name, ".")
+	if name == "" || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// printProgress prints the progress bar of a download on the current terminal line.
+//
+// Parameters:
+// - downloadedSize: the number of bytes of the file already on disk
+// - totalSize: the full size of the file
+// - sessionSize: the number of bytes received since startTime, used for the rate and remaining time
+// - startTime: the time the transfer started
+func printProgress(downloadedSize, totalSize, sessionSize int, startTime time.Time) {
+	elapsedTime := time.Since(startTime)
+
+	bytesPerSec := int(float64(sessionSize) / elapsedTime.Seconds())
+	remainingTime := time.Duration(float64(elapsedTime) / float64(sessionSize) * float64(totalSize-downloadedSize))
 
-	return resp, err, Res, Finish, TabUrl
+	fmt.Printf(
+		"\r %s / %s [%s] %.2f%% - %s/s Time Remaining: %s - Time Elapsed: %s",
+		FormatFileSize(downloadedSize),
+		FormatFileSize(totalSize),
+		progressBar(downloadedSize, totalSize, 50),
+		float64(downloadedSize)/float64(totalSize)*100,
+		FormatFileSize(bytesPerSec),
+		remainingTime.Truncate(time.Second).String(),
+		elapsedTime.Truncate(time.Second).String(),
+	)
 }
 
-// launchRequest sends a GET request to the specified URL and returns the HTTP response and any error encountered.
+// printStreamProgress prints the progress of a download whose size is unknown.
+//
+// Without a total there is no percentage or remaining time, so a marker bounces
+// across the bar while the received size, rate and elapsed time are shown.
+func printStreamProgress(downloadedSize, sessionSize int, startTime time.Time) {
+	const barWidth = 50
+	const markerWidth = 3
+	elapsedTime := time.Since(startTime)
+	bytesPerSec := int(float64(sessionSize) / elapsedTime.Seconds())
+
+	position := int(elapsedTime/(100*time.Millisecond)) % (2 * (barWidth - markerWidth))
+	if position > barWidth-markerWidth {
+		position = 2*(barWidth-markerWidth) - position
+	}
+	bar := strings.Repeat(" ", position) + "<=>" + strings.Repeat(" ", barWidth-markerWidth-position)
+
+	fmt.Printf(
+		"\r %s [%s] %s/s - Time Elapsed: %s",
+		FormatFileSize(downloadedSize),
+		bar,
+		FormatFileSize(bytesPerSec),
+		elapsedTime.Truncate(time.Second).String(),
+	)
+}
+
+// progressBar returns a bar of the given width filled in proportion to downloadedSize over totalSize.
+func progressBar(downloadedSize, totalSize, barWidth int) string {
+	progress := make([]rune, barWidth)
+	progressLength := 0
+	if totalSize > 0 {
+		progressLength = int(float64(downloadedSize) / float64(totalSize) * float64(barWidth))
+	}
+	for i := 0; i < barWidth; i++ {
+		if i < progressLength {
+			progress[i] = '='
+		} else {
+			progress[i] = ' '
+		}
+	}
+	return string(progress)
+}
+
+// savedPaths maps every URL of the run to its local copy, whether it was downloaded or
+// an existing file was kept.
+var savedPaths = make(map[string]string)
+
+// recordSavedPath remembers the local copy of a URL.
+func recordSavedPath(url, filePath string) {
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	savedPaths[url] = filePath
+}
+
+// savedPath returns the local copy of a URL of this run, if it has one.
+func savedPath(url string) (string, bool) {
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	filePath, ok := savedPaths[url]
+	return filePath, ok
+}
+
+// collectedResults returns the sizes, completion messages and URLs gathered in -i mode so far.
+func collectedResults() ([]int, string, []string) {
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	return Res, Finish, TabUrl
+}
+
+// reportCompletion reports a finished download on the terminal, in the wget-log file
+// or in the -i summary depending on the display mode.
+//
+// When the size was not known before the transfer (sizeUnknown), the number of bytes
+// actually received is reported with the completion.
+func reportCompletion(url, fileName, initString string, downloadedSize int, sizeUnknown, logFile, changeDisplay bool) error {
+	endString := ""
+	endTime := time.Now()
+	endTimeString := endTime.Format("2006-01-02 15:04:05")
+	if sizeUnknown {
+		endString += fmt.Sprintf("Content size: %s\n", FormatFileSize(downloadedSize))
+	}
+	endString += fmt.Sprintf("Download completed [%s]\n", url)
+	endString += fmt.Sprintf("finished at: %s\n", endTimeString)
+	if !logFile && !changeDisplay {
+		fmt.Print("\n\n" + endString)
+	} else if logFile {
+		file, err := os.Create("wget-log")
+		if err != nil {
+			return fmt.Errorf("error %s", err)
+		}
+		defer file.Close()
+		file.WriteString(initString + endString)
+	} else if changeDisplay {
+		resultsMu.Lock()
+		Res = append(Res, downloadedSize)
+		Finish += "finished " + fileName + "\n"
+		TabUrl = append(TabUrl, url)
+		resultsMu.Unlock()
+	}
+	return nil
+}
+
+// launchRequest sends a request to the specified URL and returns the HTTP response and any error encountered.
 //
 // url: The URL to send the request to.
+// offset: When greater than zero, only the bytes from this offset onwards are requested.
 //
 // Returns:
 // - *http.Response: The HTTP response from the server.
 // - error: Any error encountered during the request.
-func launchRequest(url string) (*http.Response, error) {
-	tlsConfig := &tls.Config{InsecureSkipVerify: true}
-	transport := &http.Transport{TLSClientConfig: tlsConfig}
-	client := &http.Client{Transport: transport}
+func launchRequest(url string, offset int) (*http.Response, error) {
+	header := make(http.Header)
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else {
+		cache.addValidators(url, header)
+	}
+	return sendRequest(Method, url, header)
+}
+
+// launchRangeRequest sends a GET request for the bytes start to end (inclusive) of the specified URL.
+func launchRangeRequest(url string, start, end int) (*http.Response, error) {
+	header := make(http.Header)
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	return sendRequest(Method, url, header)
+}
+
+// launchHeadRequest sends a HEAD request to the specified URL, to learn about a resource without downloading it.
+func launchHeadRequest(url string) (*http.Response, error) {
+	return sendRequest(http.MethodHead, url, nil)
+}
+
+// sendRequest sends a request with the given method to the specified URL, adding the given headers
+// to those of the command line.
+func sendRequest(method, url string, header http.Header) (*http.Response, error) {
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := newRequest(method, url)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", user_agent)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	// Credentials are only sent in answer to a challenge, never straight from the URL
+	userinfo := req.URL.User
+	req.URL.User = nil
+	originHost := req.URL.Host
+	authorize(req)
+	waitCrawlDelay(req.URL)
 
 	resp, err := client.Do(req)
-	return resp, err
+	if err != nil {
+		return resp, certificateError(req.URL.Hostname(), err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		if retry := answerChallenge(resp, originHost, userinfo); retry != nil {
+			resp.Body.Close()
+			resp, err = client.Do(retry)
+			if err != nil {
+				return resp, certificateError(retry.URL.Hostname(), err)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// parseContentRange parses a "bytes start-end/total" Content-Range header.
+//
+// It returns the first byte position and the complete length of the resource,
+// or -1 for the length when the server reports it as unknown ("*").
+func parseContentRange(contentRange string) (int, int, error) {
+	var start, end int
+	var total string
+	_, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &start, &end, &total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q: %v", contentRange, err)
+	}
+	if total == "*" {
+		return start, -1, nil
+	}
+	size, err := strconv.Atoi(total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q: %v", contentRange, err)
+	}
+	return start, size, nil
 }
@@ -0,0 +1,89 @@
+package wget
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	netrcOnce    sync.Once
+	netrcEntries map[string]credentials
+)
+
+// netrcCredentials returns the login and password of host in the .netrc file.
+//
+// The file is $NETRC or ~/.netrc. A "default" entry matches hosts without their own
+// "machine" entry.
+func netrcCredentials(host string) (credentials, bool) {
+	netrcOnce.Do(func() {
+		netrcEntries = loadNetrc()
+	})
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	creds, ok := netrcEntries[strings.ToLower(host)]
+	if !ok {
+		creds, ok = netrcEntries[""]
+	}
+	return creds, ok
+}
+
+// loadNetrc parses the .netrc file into credentials by machine name, "" holding the default entry.
+func loadNetrc() map[string]credentials {
+	entries := make(map[string]credentials)
+	fileName := os.Getenv("NETRC")
+	if fileName == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return entries
+		}
+		fileName = filepath.Join(home, ".netrc")
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return entries
+	}
+
+	var machine *string
+	var creds credentials
+	flush := func() {
+		if machine != nil {
+			entries[*machine] = creds
+		}
+		machine, creds = nil, credentials{}
+	}
+	tokens := strings.Fields(string(data))
+	for i := 0; i < len(tokens); i++ {
+		next := func() string {
+			if i+1 < len(tokens) {
+				i++
+				return tokens[i]
+			}
+			return ""
+		}
+		switch tokens[i] {
+		case "machine":
+			flush()
+			name := strings.ToLower(next())
+			machine = &name
+		case "default":
+			flush()
+			name := ""
+			machine = &name
+		case "login":
+			creds.user = next()
+		case "password":
+			creds.password = next()
+		case "account":
+			next()
+		case "macdef":
+			// Macros run until an empty line, which Fields does not keep: skip the rest
+			flush()
+			return entries
+		}
+	}
+	flush()
+	return entries
+}
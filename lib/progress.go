@@ -0,0 +1,147 @@
+package wget
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// board is the multi-line progress display used by concurrent -i downloads.
+// It is nil when downloads report their progress one at a time.
+var board *progressBoard
+
+// progressBoard draws one progress bar per active transfer and an aggregate bar below them.
+type progressBoard struct {
+	mu        sync.Mutex
+	transfers map[string]*transfer
+	order     []string
+	files     int
+	finished  int
+	lines     int
+}
+
+// transfer is the progress of a single download shown on the board.
+type transfer struct {
+	name           string
+	downloadedSize int
+	totalSize      int
+	done           bool
+}
+
+// newProgressBoard creates a board for a batch of the given number of files.
+func newProgressBoard(files int) *progressBoard {
+	return &progressBoard{transfers: make(map[string]*transfer), files: files}
+}
+
+// update records the progress of the transfer of url. It does nothing on a nil board.
+func (b *progressBoard) update(url, name string, downloadedSize, totalSize int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.transfers[url]
+	if !ok {
+		t = &transfer{}
+		b.transfers[url] = t
+		b.order = append(b.order, url)
+	}
+	t.name, t.downloadedSize, t.totalSize = name, downloadedSize, totalSize
+}
+
+// finish removes the transfer of url from the active rows, keeping it in the aggregate.
+func (b *progressBoard) finish(url string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.transfers[url]; ok {
+		t.done = true
+	}
+	b.finished++
+}
+
+// run redraws the board until stop is closed, then draws it a last time.
+func (b *progressBoard) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			b.render()
+			return
+		case <-ticker.C:
+			b.render()
+		}
+	}
+}
+
+// render redraws the board in place, moving the cursor back over the previous drawing.
+func (b *progressBoard) render() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var out strings.Builder
+	if b.lines > 0 {
+		fmt.Fprintf(&out, "\033[%dA", b.lines)
+	}
+	lines := 0
+	downloaded, total := 0, 0
+	for _, url := range b.order {
+		t := b.transfers[url]
+		downloaded += t.downloadedSize
+		if t.totalSize > 0 {
+			total += t.totalSize
+		}
+		if t.done {
+			continue
+		}
+		fmt.Fprintf(&out, "\033[K%s\n", formatProgressLine(t.name, t.downloadedSize, t.totalSize))
+		lines++
+	}
+	label := fmt.Sprintf("Total (%d/%d files)", b.finished, b.files)
+	fmt.Fprintf(&out, "\033[K%s\n", formatProgressLine(label, downloaded, total))
+	lines++
+	// Clear the rows left over by transfers that finished since the last drawing
+	for i := lines; i < b.lines; i++ {
+		out.WriteString("\033[K\n")
+	}
+	if b.lines > lines {
+		fmt.Fprintf(&out, "\033[%dA", b.lines-lines)
+	}
+	b.lines = lines
+	fmt.Print(out.String())
+}
+
+// formatProgressLine formats a single row of the board.
+func formatProgressLine(name string, downloadedSize, totalSize int) string {
+	const nameWidth = 30
+	if len(name) > nameWidth {
+		name = "..." + name[len(name)-nameWidth+3:]
+	}
+	if totalSize < 0 {
+		return fmt.Sprintf("%-*s %10s", nameWidth, name, FormatFileSize(downloadedSize))
+	}
+	percent := 0.0
+	if totalSize > 0 {
+		percent = float64(downloadedSize) / float64(totalSize) * 100
+	}
+	return fmt.Sprintf(
+		"%-*s %10s / %-10s [%s] %6.2f%%",
+		nameWidth,
+		name,
+		FormatFileSize(downloadedSize),
+		FormatFileSize(totalSize),
+		progressBar(downloadedSize, totalSize, 30),
+		percent,
+	)
+}
+
+// isTerminal reports whether the standard output is an interactive terminal.
+func isTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
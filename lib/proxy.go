@@ -0,0 +1,112 @@
+package wget
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// proxyFromOptions returns the Proxy function of the transport.
+//
+// The proxies come from the --http-proxy and --https-proxy flags, or else from the
+// http_proxy and https_proxy environment variables. Hosts matching --no-proxy-hosts or
+// no_proxy are reached directly. HTTPS requests through an HTTP proxy are tunneled
+// with CONNECT by the transport.
+func proxyFromOptions() func(*http.Request) (*url.URL, error) {
+	httpProxy := firstNonEmpty(HTTPProxy, getenv("http_proxy"))
+	httpsProxy := firstNonEmpty(HTTPSProxy, getenv("https_proxy"))
+	noProxy := firstNonEmpty(NoProxyHosts, getenv("no_proxy"))
+
+	return func(req *http.Request) (*url.URL, error) {
+		if NoProxy {
+			return nil, nil
+		}
+		proxy := httpProxy
+		if req.URL.Scheme == "https" {
+			proxy = httpsProxy
+		}
+		if proxy == "" || matchNoProxy(noProxy, req.URL.Hostname(), req.URL.Port()) {
+			return nil, nil
+		}
+		return parseProxyURL(proxy)
+	}
+}
+
+// parseProxyURL parses a proxy address, defaulting to the http scheme, and adds the
+// --proxy-user and --proxy-password credentials sent in Proxy-Authorization.
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if ProxyUser != "" {
+		proxyURL.User = url.UserPassword(ProxyUser, ProxyPassword)
+	}
+	return proxyURL, nil
+}
+
+// matchNoProxy reports whether a host is excluded from proxying by a no_proxy list.
+//
+// Entries are separated by commas and may be "*" for every host, an IP address, a CIDR
+// range such as "10.0.0.0/8", or a domain matching itself and its subdomains
+// (a leading dot is optional). An entry may end with ":port" to match that port only.
+func matchNoProxy(noProxy, host, port string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if entryHost, entryPort, err := net.SplitHostPort(entry); err == nil {
+			if entryPort != port {
+				continue
+			}
+			entry = entryHost
+		}
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(entry, "*")
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// getenv returns the lowercase environment variable, or its uppercase form when unset.
+func getenv(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToUpper(name))
+}
+
+// firstNonEmpty returns the first of its arguments that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package wget
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestMatchNoProxy(t *testing.T) {
+	tests := []struct {
+		noProxy, host, port string
+		want                bool
+	}{
+		{"", "example.com", "", false},
+		{"*", "example.com", "", true},
+		{"example.com", "example.com", "", true},
+		{"example.com", "www.example.com", "", true},
+		{"example.com", "notexample.com", "", false},
+		{".example.com", "example.com", "", true},
+		{".example.com", "www.example.com", "", true},
+		{".example.com", "badexample.com", "", false},
+		{"*.example.com", "www.example.com", "", true},
+		{"EXAMPLE.com", "www.Example.COM", "", true},
+		{"other.org, example.com", "example.com", "", true},
+		{"other.org,,", "example.com", "", false},
+		{"example.com:8080", "example.com", "8080", true},
+		{"example.com:8080", "example.com", "443", false},
+		{"example.com:8080", "example.com", "", false},
+		{".example.com:8080", "www.example.com", "8080", true},
+		{"10.0.0.1", "10.0.0.1", "", true},
+		{"10.0.0.1", "10.0.0.2", "", false},
+		{"10.0.0.1:80", "10.0.0.1", "80", true},
+		{"10.0.0.1:80", "10.0.0.1", "81", false},
+		{"10.0.0.0/8", "10.1.2.3", "", true},
+		{"10.0.0.0/8", "11.1.2.3", "", false},
+		{"10.0.0.0/8", "10.example.com", "", false},
+		{"192.168.1.0/24", "192.168.1.200", "443", true},
+		{"::1", "::1", "", true},
+		{"[::1]:8080", "::1", "8080", true},
+		{"[::1]:8080", "::1", "80", false},
+		{"fd00::/8", "fd00::1", "", true},
+		{"fd00::/8", "fe80::1", "", false},
+	}
+	for _, test := range tests {
+		if got := matchNoProxy(test.noProxy, test.host, test.port); got != test.want {
+			t.Errorf("matchNoProxy(%q, %q, %q) = %v, want %v", test.noProxy, test.host, test.port, got, test.want)
+		}
+	}
+}
+
+// connectProxy is an HTTP proxy that only tunnels CONNECT requests, recording their target
+// and Proxy-Authorization header.
+type connectProxy struct {
+	mu      sync.Mutex
+	targets []string
+	auth    string
+}
+
+func (p *connectProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	p.mu.Lock()
+	p.targets = append(p.targets, r.Host)
+	p.auth = r.Header.Get("Proxy-Authorization")
+	p.mu.Unlock()
+
+	upstream, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+	conn, buffered, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	go io.Copy(upstream, buffered)
+	io.Copy(conn, upstream)
+}
+
+// setProxyOptions sets the proxy options for a test and restores them when it ends.
+func setProxyOptions(t *testing.T, httpsProxy, noProxyHosts, user, password string) {
+	saved := []string{HTTPSProxy, NoProxyHosts, ProxyUser, ProxyPassword}
+	savedNoProxy := NoProxy
+	t.Cleanup(func() {
+		HTTPSProxy, NoProxyHosts, ProxyUser, ProxyPassword = saved[0], saved[1], saved[2], saved[3]
+		NoProxy = savedNoProxy
+	})
+	HTTPSProxy, NoProxyHosts, ProxyUser, ProxyPassword = httpsProxy, noProxyHosts, user, password
+	NoProxy = false
+	t.Setenv("https_proxy", "")
+	t.Setenv("no_proxy", "")
+}
+
+func TestProxyFromOptionsConnect(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tunneled"))
+	}))
+	defer target.Close()
+	proxy := &connectProxy{}
+	proxyServer := httptest.NewServer(proxy)
+	defer proxyServer.Close()
+	targetURL, _ := url.Parse(target.URL)
+
+	tests := []struct {
+		name         string
+		noProxyHosts string
+		tunneled     bool
+	}{
+		{"tunneled", "", true},
+		{"other host excluded", "example.com", true},
+		{"host excluded", targetURL.Hostname(), false},
+		{"port excluded", "127.0.0.1:" + targetURL.Port(), false},
+		{"other port excluded", "127.0.0.1:1", true},
+		{"range excluded", "127.0.0.0/8", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setProxyOptions(t, proxyServer.Listener.Addr().String(), test.noProxyHosts, "user", "secret")
+			proxy.mu.Lock()
+			proxy.targets, proxy.auth = nil, ""
+			proxy.mu.Unlock()
+
+			// The test server certificate is trusted by the transport of its own client
+			transport := target.Client().Transport.(*http.Transport).Clone()
+			transport.Proxy = proxyFromOptions()
+			defer transport.CloseIdleConnections()
+			resp, err := (&http.Client{Transport: transport}).Get(target.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "tunneled" {
+				t.Errorf("body = %q, want %q", body, "tunneled")
+			}
+
+			proxy.mu.Lock()
+			defer proxy.mu.Unlock()
+			if !test.tunneled {
+				if len(proxy.targets) != 0 {
+					t.Errorf("request went through the proxy to %v, want a direct connection", proxy.targets)
+				}
+				return
+			}
+			if len(proxy.targets) != 1 || proxy.targets[0] != targetURL.Host {
+				t.Fatalf("CONNECT targets = %v, want [%s]", proxy.targets, targetURL.Host)
+			}
+			wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+			if proxy.auth != wantAuth {
+				t.Errorf("Proxy-Authorization = %q, want %q", proxy.auth, wantAuth)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package wget
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// stringList is a flag that can be repeated, collecting every value given.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// newRequest creates a request for url with the body and headers of the command line.
+// Only requests sent with Method carry the body, never the HEAD, robots.txt and other
+// requests wget makes on its own.
+func newRequest(method, url string) (*http.Request, error) {
+	var body io.Reader
+	if RequestBody != nil && method == Method {
+		body = bytes.NewReader(RequestBody)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", UserAgent)
+	if Referer != "" {
+		req.Header.Set("Referer", Referer)
+	}
+	if body != nil && isFormBody {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	// A --header replaces the default of the same name, and repeating it sends every value
+	for _, header := range Headers {
+		name, _, _ := strings.Cut(header, ":")
+		req.Header.Del(strings.TrimSpace(name))
+	}
+	for _, header := range Headers {
+		name, value, _ := strings.Cut(header, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+		} else if value != "" {
+			req.Header.Add(name, value)
+		}
+	}
+	return req, nil
+}
+
+// isFormBody is set when the body comes from --post-data or --post-file, which are sent as a form.
+var isFormBody bool
+
+// setRequestOptions validates the request flags and loads the request body.
+//
+// --post-data and --post-file send a form with POST, while --body-data and --body-file
+// send the body as is with the --method given (POST by default).
+func setRequestOptions(postData, postFile, bodyData, bodyFile string) error {
+	sources := 0
+	for _, source := range []string{postData, postFile, bodyData, bodyFile} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of --post-data, --post-file, --body-data and --body-file can be given")
+	}
+
+	var err error
+	switch {
+	case postData != "":
+		RequestBody, isFormBody = []byte(postData), true
+	case postFile != "":
+		RequestBody, err = os.ReadFile(postFile)
+		isFormBody = true
+	case bodyData != "":
+		RequestBody = []byte(bodyData)
+	case bodyFile != "":
+		RequestBody, err = os.ReadFile(bodyFile)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading request body: %v", err)
+	}
+
+	Method = strings.ToUpper(Method)
+	if Method == "" {
+		Method = http.MethodGet
+		if RequestBody != nil {
+			Method = http.MethodPost
+		}
+	}
+	if isFormBody && Method != http.MethodPost {
+		return fmt.Errorf("--post-data and --post-file can only be used with POST, use --body-data or --body-file")
+	}
+
+	for _, header := range Headers {
+		name, _, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid header %q, expected \"Name: value\"", header)
+		}
+	}
+	return nil
+}
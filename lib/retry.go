@@ -0,0 +1,130 @@
+package wget
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// launchRequestWithRetry sends a request like launchRequest, retrying network failures
+// and retryable HTTP status codes according to Tries, WaitRetry and RetryOnHTTPError.
+//
+// When the server answers with a Retry-After header, its delay is used instead of the
+// exponential backoff. The last response or error is returned once the attempts run out.
+func launchRequestWithRetry(url string, offset int) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := launchRequest(url, offset)
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if !isRetryableError(err) || !shouldRetry(attempt) {
+				return resp, err
+			}
+			if board == nil {
+				fmt.Printf("Error fetching %s: %v\n", url, err)
+			}
+		case isRetryableStatus(resp.StatusCode):
+			if !shouldRetry(attempt) {
+				return resp, nil
+			}
+			if board == nil {
+				fmt.Printf("Server returned %s for %s\n", resp.Status, url)
+			}
+			wait = parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if wait <= 0 {
+			wait = retryDelay(attempt)
+		}
+		if board == nil {
+			fmt.Printf("Retrying in %s (attempt %d)...\n", wait.Truncate(time.Millisecond), attempt+1)
+		}
+		time.Sleep(wait)
+	}
+}
+
+// shouldRetry reports whether another attempt is allowed after the given number of attempts.
+// A Tries value of 0 means retrying forever.
+func shouldRetry(attempt int) bool {
+	return Tries <= 0 || attempt < Tries
+}
+
+// retryDelay returns the time to wait before the attempt following the given one.
+//
+// The delay doubles with every attempt starting at one second, is capped at WaitRetry
+// and is then randomized between half and all of its value so that parallel
+// clients do not retry in lockstep.
+func retryDelay(attempt int) time.Duration {
+	maxDelay := time.Duration(WaitRetry) * time.Second
+	if maxDelay <= 0 {
+		return 0
+	}
+	delay := time.Second
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+// It returns 0 when the header is missing or invalid.
+func parseRetryAfter(retryAfter string) time.Duration {
+	if retryAfter == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// isRetryableStatus reports whether a response status code is listed in RetryOnHTTPError.
+func isRetryableStatus(statusCode int) bool {
+	for _, code := range RetryOnHTTPError {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryableError reports whether a request or transfer error is transient.
+//
+// Dropped connections, timeouts and truncated bodies are retried, while errors such as
+// an invalid URL, an unknown host or a rejected certificate would fail the same way again.
+// A refused connection is only retried with --retry-connrefused.
+func isRetryableError(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return RetryConnRefused
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
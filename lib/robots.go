@@ -0,0 +1,252 @@
+package wget
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robotsRule is an Allow or Disallow line of robots.txt.
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsRules are the robots.txt rules of a host that apply to our User-Agent.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	// lastRequest is when the crawl last contacted the host, for the Crawl-delay
+	lastRequest time.Time
+}
+
+var (
+	robotsMu sync.Mutex
+	// robotsCache holds the rules of every host crawled, by scheme and host
+	robotsCache = make(map[string]*robotsRules)
+)
+
+// robotsAllowed reports whether robots.txt lets the crawl fetch a URL.
+//
+// The robots.txt of a host is fetched the first time one of its URLs is crawled. It only
+// applies to --mirror and -p, and not with -e robots=off.
+func robotsAllowed(link string) bool {
+	if !recursive || !Robots {
+		return true
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return true
+	}
+	rules := hostRobots(u)
+	return rules.allowed(u.EscapedPath() + queryString(u))
+}
+
+// hostRobots returns the robots.txt rules of the host of a URL, fetching them if needed.
+func hostRobots(u *url.URL) *robotsRules {
+	key := u.Scheme + "://" + u.Host
+	robotsMu.Lock()
+	rules, ok := robotsCache[key]
+	robotsMu.Unlock()
+	if ok {
+		return rules
+	}
+
+	// A missing or unreadable robots.txt allows everything
+	rules = &robotsRules{}
+	resp, err := sendRequest(http.MethodGet, key+"/robots.txt", nil)
+	if err == nil {
+		if resp.StatusCode == http.StatusOK {
+			rules = parseRobots(resp.Body, UserAgent)
+		}
+		resp.Body.Close()
+	}
+	if rules.crawlDelay > 0 && board == nil {
+		fmt.Printf("Honoring Crawl-delay of %s for %s\n", rules.crawlDelay, u.Host)
+	}
+	robotsMu.Lock()
+	robotsCache[key] = rules
+	robotsMu.Unlock()
+	return rules
+}
+
+// waitCrawlDelay waits until the Crawl-delay of the host of a request has passed since the
+// last request the crawl sent it. Hosts whose robots.txt was not read yet are not delayed.
+func waitCrawlDelay(u *url.URL) {
+	if !recursive || !Robots {
+		return
+	}
+	robotsMu.Lock()
+	rules, ok := robotsCache[u.Scheme+"://"+u.Host]
+	if !ok || rules.crawlDelay == 0 {
+		robotsMu.Unlock()
+		return
+	}
+	wait := time.Until(rules.lastRequest.Add(rules.crawlDelay))
+	if wait < 0 {
+		wait = 0
+	}
+	rules.lastRequest = time.Now().Add(wait)
+	robotsMu.Unlock()
+	time.Sleep(wait)
+}
+
+// parseRobots reads the rules of a robots.txt that apply to userAgent.
+//
+// The groups naming the product token of userAgent (e.g. "golang" for "Golang Mirror v. 2.0")
+// are used if there are any, the "*" groups otherwise.
+func parseRobots(r io.Reader, userAgent string) *robotsRules {
+	token := strings.ToLower(userAgent)
+	if fields := strings.FieldsFunc(token, func(c rune) bool { return c == ' ' || c == '/' }); len(fields) > 0 {
+		token = fields[0]
+	}
+	var own, others robotsRules
+	var ownFound bool
+	// The groups the current lines belong to
+	var inOwn, inAny, readingAgents bool
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if name == "user-agent" {
+			// Consecutive User-agent lines open a single group
+			if !readingAgents {
+				inOwn, inAny = false, false
+			}
+			readingAgents = true
+			agent := strings.ToLower(value)
+			if agent == "*" {
+				inAny = true
+			} else if agent != "" && strings.Contains(token, agent) {
+				inOwn, ownFound = true, true
+			}
+			continue
+		}
+		readingAgents = false
+		var groups []*robotsRules
+		if inOwn {
+			groups = append(groups, &own)
+		}
+		if inAny {
+			groups = append(groups, &others)
+		}
+		for _, group := range groups {
+			switch name {
+			case "allow", "disallow":
+				// An empty Disallow allows everything
+				if value != "" {
+					group.rules = append(group.rules, robotsRule{allow: name == "allow", pattern: value})
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					group.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+	if ownFound {
+		return &own
+	}
+	return &others
+}
+
+// allowed reports whether the rules allow a path, with its query string.
+//
+// The rule with the longest matching pattern wins, an Allow winning a tie.
+func (r *robotsRules) allowed(urlPath string) bool {
+	allowed := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, urlPath) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// matchRobotsPattern reports whether a robots.txt path pattern matches the start of a path.
+// "*" matches any sequence of characters and a final "$" anchors the pattern at the end.
+func matchRobotsPattern(pattern, urlPath string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(urlPath, parts[0]) {
+		return false
+	}
+	rest := urlPath[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// The last part of an anchored pattern must end the path
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// queryString returns the query of a URL with its "?", or "" if it has none.
+func queryString(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+	return "?" + u.RawQuery
+}
+
+// metaRobots returns the nofollow and noindex directives of a <meta name="robots"> tag.
+// "none" stands for both.
+func metaRobots(content string) (nofollow, noindex bool) {
+	for _, directive := range strings.Split(strings.ToLower(content), ",") {
+		switch strings.TrimSpace(directive) {
+		case "nofollow":
+			nofollow = true
+		case "noindex":
+			noindex = true
+		case "none":
+			nofollow, noindex = true, true
+		}
+	}
+	return nofollow, noindex
+}
+
+// executeCommand runs a wgetrc-style "name=value" command given with -e.
+func executeCommand(command string) error {
+	name, value, ok := strings.Cut(command, "=")
+	if !ok {
+		return fmt.Errorf("invalid command %q, expected NAME=VALUE", command)
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch name {
+	case "robots":
+		switch value {
+		case "on", "yes", "1":
+			Robots = true
+		case "off", "no", "0":
+			Robots = false
+		default:
+			return fmt.Errorf("invalid value %q for robots, expected on or off", value)
+		}
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package wget
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// minSegmentSize is the smallest range worth its own connection.
+const minSegmentSize = 256 * 1024
+
+// probeRanges checks whether the server accepts byte ranges for the given URL.
+//
+// It asks for the first byte only and returns the response together with the full size
+// of the resource taken from its Content-Range header.
+func probeRanges(url string) (*http.Response, int, error) {
+	resp, err := launchRangeRequest(url, 0, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent || strings.EqualFold(resp.Header.Get("Accept-Ranges"), "none") {
+		return resp, 0, fmt.Errorf("server does not support byte ranges")
+	}
+	_, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return resp, 0, err
+	}
+	if total < 0 {
+		return resp, 0, fmt.Errorf("server did not report the file size")
+	}
+	return resp, total, nil
+}
+
+// downloadSegmented downloads a file of totalSize bytes as Segments ranges fetched concurrently.
+//
+// The local file is preallocated and every segment writes at its own offset. The progress of
+// all segments is merged into a single progress bar, and a failed segment is retried on its own
+// from the last byte it wrote.
+func downloadSegmented(resp *http.Response, url, fileName, filePath string, totalSize int, logFile bool, rateLimit int, changeDisplay bool) error {
+	segments := Segments
+	if totalSize/segments < minSegmentSize {
+		segments = totalSize / minSegmentSize
+	}
+
+	startTime := time.Now()
+	initString := ""
+	initString += fmt.Sprintf("Start at: %s\n", startTime.Format("2006-01-02 15:04:05"))
+	initString += fmt.Sprintf("Sending request, awaiting response... status %s\n", resp.Status)
+	initString += fmt.Sprintf("Content size: %s\n", FormatFileSize(totalSize))
+	initString += fmt.Sprintf("Saving file to: %s\n", filePath)
+	initString += fmt.Sprintf("Segments: %d\n", segments)
+
+	// Segments cannot be resumed, so the .part file is removed unless the download completes
+	tempPath := partPath(filePath)
+	completed := false
+	defer func() {
+		if !completed {
+			os.Remove(tempPath)
+		}
+	}()
+	localFile, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+	defer localFile.Close()
+	err = localFile.Truncate(int64(totalSize))
+	if err != nil {
+		return err
+	}
+
+	if !logFile && !changeDisplay {
+		fmt.Print(initString)
+	}
+
+	var downloadedSize int64
+	var wg sync.WaitGroup
+	errs := make([]error, segments)
+	segmentSize := totalSize / segments
+	for i := 0; i < segments; i++ {
+		start := i * segmentSize
+		end := start + segmentSize - 1
+		if i == segments-1 {
+			end = totalSize - 1
+		}
+		wg.Add(1)
+		go func(i, start, end int) {
+			defer wg.Done()
+			errs[i] = downloadSegment(url, localFile, start, end, &downloadedSize, rateLimit/segments)
+		}(i, start, end)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		case <-ticker.C:
+		}
+		size := int(atomic.LoadInt64(&downloadedSize))
+		if !logFile && !changeDisplay {
+			printProgress(size, totalSize, size, startTime)
+		} else if changeDisplay {
+			board.update(url, fileName, size, totalSize)
+		}
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("segment %d of %d failed: %v", i+1, segments, err)
+		}
+	}
+	// Segments arrive out of order, the file is hashed once complete
+	hasher, err := newFileHasher()
+	if err == nil {
+		err = hasher.hashFile(tempPath, -1)
+	}
+	if err == nil {
+		err = verifyChecksum(hasher, url, fileName, tempPath, filePath)
+	}
+	if err != nil {
+		return err
+	}
+	err = commitPartFile(localFile, tempPath, filePath)
+	if err != nil {
+		return err
+	}
+	completed = true
+	recordSum(hasher, filePath)
+	if Timestamping {
+		err = setModTime(filePath, resp)
+		if err != nil {
+			return err
+		}
+	}
+	cache.store(url, filePath, resp, totalSize)
+	recordSavedPath(url, filePath)
+	return reportCompletion(url, fileName, initString, totalSize, false, logFile, changeDisplay)
+}
+
+// downloadSegment fetches the bytes start to end (inclusive) of the URL into the file at the same offsets.
+//
+// The number of bytes written is added to downloadedSize as they arrive. On a network failure or a
+// retryable status code, the segment is requested again from where it stopped, following the
+// same retry policy as whole downloads.
+func downloadSegment(url string, localFile *os.File, start, end int, downloadedSize *int64, rateLimit int) error {
+	for attempt := 1; ; attempt++ {
+		resp, err := launchRangeRequest(url, start, end)
+		retryable := err != nil && isRetryableError(err)
+		if err == nil {
+			if resp.StatusCode == http.StatusPartialContent {
+				var n int
+				n, err = writeSegment(localFile, resp.Body, start, downloadedSize, rateLimit)
+				start += n
+				if err == nil && start <= end {
+					err = io.ErrUnexpectedEOF
+				}
+				retryable = err != nil && isRetryableError(err)
+			} else {
+				err = fmt.Errorf("server returned %s", resp.Status)
+				retryable = isRetryableStatus(resp.StatusCode)
+			}
+			resp.Body.Close()
+		}
+
+		if err == nil {
+			return nil
+		}
+		if !retryable || !shouldRetry(attempt) {
+			return err
+		}
+		time.Sleep(retryDelay(attempt))
+	}
+}
+
+// writeSegment copies body into the file starting at offset and returns the number of bytes written.
+func writeSegment(localFile *os.File, body io.Reader, offset int, downloadedSize *int64, rateLimit int) (int, error) {
+	written := 0
+	startTime := time.Now()
+	buffer := make([]byte, 32*1024)
+	for {
+		chunk, readErr := body.Read(buffer)
+		if chunk > 0 {
+			_, err := localFile.WriteAt(buffer[:chunk], int64(offset+written))
+			if err != nil {
+				return written, err
+			}
+			written += chunk
+			atomic.AddInt64(downloadedSize, int64(chunk))
+		}
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+
+		if rateLimit > 0 {
+			expected := time.Duration(float64(written) / float64(rateLimit) * float64(time.Second))
+			if wait := expected - time.Since(startTime); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+	}
+}
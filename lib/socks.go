@@ -0,0 +1,49 @@
+package wget
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/proxy"
+)
+
+// socks5DialContext returns a dial function connecting through the --socks5 proxy.
+//
+// Host names are resolved by the proxy unless --socks5-local-dns is given, in which case
+// they are resolved locally and the proxy only sees IP addresses.
+func socks5DialContext() (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	var auth *proxy.Auth
+	if Socks5User != "" {
+		auth = &proxy.Auth{User: Socks5User, Password: Socks5Password}
+	}
+	direct := &net.Dialer{}
+	dialer, err := proxy.SOCKS5("tcp", Socks5, auth, direct)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring SOCKS5 proxy: %v", err)
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 dialer does not support contexts")
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if Socks5LocalDNS {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			if net.ParseIP(host) == nil {
+				ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				if len(ips) == 0 {
+					return nil, fmt.Errorf("no address found for %s", host)
+				}
+				addr = net.JoinHostPort(ips[0].IP.String(), port)
+			}
+		}
+		return contextDialer.DialContext(ctx, network, addr)
+	}, nil
+}
@@ -0,0 +1,37 @@
+package wget
+
+import (
+	"net/http"
+	"os"
+	"time"
+)
+
+// isUpToDate reports whether the local file is as recent as the resource on the server
+// and of the same size, according to a HEAD request (-N). The HEAD response is returned
+// whenever the server answered.
+func isUpToDate(url string, info os.FileInfo) (*http.Response, bool) {
+	resp, err := launchHeadRequest(url)
+	if err != nil {
+		return nil, false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp, false
+	}
+
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil || lastModified.After(info.ModTime()) {
+		return resp, false
+	}
+	return resp, resp.ContentLength < 0 || resp.ContentLength == info.Size()
+}
+
+// setModTime sets the modification time of a downloaded file to the Last-Modified
+// time of its response, so that the next -N run can compare them.
+func setModTime(filePath string, resp *http.Response) error {
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return nil
+	}
+	return os.Chtimes(filePath, time.Now(), lastModified)
+}
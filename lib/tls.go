@@ -0,0 +1,312 @@
+package wget
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	tlsConfigOnce sync.Once
+	tlsConfig     *tls.Config
+	tlsConfigErr  error
+)
+
+// getTLSConfig returns the TLS configuration shared by every request of the run.
+//
+// Certificates are verified against the system roots plus the CAs given with
+// --ca-certificate and --ca-directory, unless --no-check-certificate was given.
+func getTLSConfig() (*tls.Config, error) {
+	tlsConfigOnce.Do(func() {
+		tlsConfig, tlsConfigErr = newTLSConfig()
+	})
+	return tlsConfig, tlsConfigErr
+}
+
+// newTLSConfig builds the TLS configuration from the command line options.
+func newTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: !CheckCertificate}
+
+	if CheckCertificate && (CACertificate != "" || CADirectory != "") {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if CACertificate != "" {
+			err = appendCertificates(pool, CACertificate)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if CADirectory != "" {
+			entries, err := os.ReadDir(CADirectory)
+			if err != nil {
+				return nil, fmt.Errorf("error reading CA directory: %v", err)
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					continue
+				}
+				// Hashed links and unrelated files are skipped, only valid PEM files count
+				_ = appendCertificates(pool, filepath.Join(CADirectory, entry.Name()))
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if Certificate != "" {
+		certificate, err := loadClientCertificate(Certificate, PrivateKey, PrivateKeyPassword)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	minVersion, maxVersion, err := parseSecureProtocol(SecureProtocol)
+	if err != nil {
+		return nil, err
+	}
+	config.MinVersion, config.MaxVersion = minVersion, maxVersion
+
+	if Ciphers != "" {
+		config.CipherSuites, err = parseCipherSuites(Ciphers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if PinnedPubKey != "" {
+		pins, err := loadPinnedKeys(PinnedPubKey)
+		if err != nil {
+			return nil, err
+		}
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPinnedKey(state, pins)
+		}
+	}
+	return config, nil
+}
+
+// appendCertificates adds the PEM certificates of a file to the pool.
+func appendCertificates(pool *x509.CertPool, fileName string) error {
+	pem, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("error reading CA certificate: %v", err)
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no PEM certificate found in %s", fileName)
+	}
+	return nil
+}
+
+// loadClientCertificate loads the PEM client certificate and private key used for mutual TLS.
+//
+// The key defaults to the certificate file when empty. An encrypted PKCS#8 key
+// ("ENCRYPTED PRIVATE KEY") is decrypted with the given password.
+func loadClientCertificate(certFile, keyFile, password string) (tls.Certificate, error) {
+	if keyFile == "" {
+		keyFile = certFile
+	}
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error reading certificate: %v", err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error reading private key: %v", err)
+	}
+
+	var keyBlock *pem.Block
+	for rest := keyPEM; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			keyBlock = block
+			break
+		}
+	}
+	if keyBlock == nil {
+		return tls.Certificate{}, fmt.Errorf("no PEM private key found in %s", keyFile)
+	}
+	if keyBlock.Type == "ENCRYPTED PRIVATE KEY" {
+		if password == "" {
+			return tls.Certificate{}, fmt.Errorf("private key %s is encrypted, use --private-key-password", keyFile)
+		}
+		der, err := decryptPKCS8(keyBlock.Bytes, password)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("error decrypting private key: %v", err)
+		}
+		keyBlock = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	// Legacy PEM encryption can't detect tampering and is deprecated in Go
+	if _, ok := keyBlock.Headers["DEK-Info"]; ok {
+		return tls.Certificate{}, fmt.Errorf("private key %s uses legacy PEM encryption, convert it with \"openssl pkcs8 -topk8 -v2 aes-256-cbc\"", keyFile)
+	}
+
+	certificate, err := tls.X509KeyPair(certPEM, pem.EncodeToMemory(keyBlock))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error loading client certificate: %v", err)
+	}
+	return certificate, nil
+}
+
+// tlsVersions maps the --secure-protocol names to TLS versions.
+var tlsVersions = map[string]uint16{
+	"tlsv1":   tls.VersionTLS10,
+	"tlsv1_1": tls.VersionTLS11,
+	"tlsv1_2": tls.VersionTLS12,
+	"tlsv1_3": tls.VersionTLS13,
+}
+
+// parseSecureProtocol parses a --secure-protocol value of the form "auto", "MIN" or "MIN:MAX",
+// such as "TLSv1_2" or "TLSv1_2:TLSv1_3". A zero version leaves the Go default in place.
+func parseSecureProtocol(protocol string) (uint16, uint16, error) {
+	if protocol == "" || strings.EqualFold(protocol, "auto") {
+		return 0, 0, nil
+	}
+	minName, maxName, _ := strings.Cut(protocol, ":")
+	minVersion, ok := tlsVersions[strings.ToLower(minName)]
+	if !ok {
+		return 0, 0, fmt.Errorf("unsupported secure protocol: %s", minName)
+	}
+	if maxName == "" {
+		return minVersion, 0, nil
+	}
+	maxVersion, ok := tlsVersions[strings.ToLower(maxName)]
+	if !ok {
+		return 0, 0, fmt.Errorf("unsupported secure protocol: %s", maxName)
+	}
+	if maxVersion < minVersion {
+		return 0, 0, fmt.Errorf("invalid secure protocol range: %s", protocol)
+	}
+	return minVersion, maxVersion, nil
+}
+
+// parseCipherSuites parses a comma-separated list of cipher suite names as printed by Go,
+// such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256". TLS 1.3 suites are not configurable.
+func parseCipherSuites(list string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+	var ids []uint16
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// loadPinnedKeys parses a --pinnedpubkey value into base64 SHA-256 hashes of public keys.
+//
+// The value is either a ";"-separated list of "sha256//<base64>" hashes or the path of a
+// PEM file holding the public key or certificate of the server.
+func loadPinnedKeys(pinned string) ([]string, error) {
+	if strings.HasPrefix(pinned, "sha256//") {
+		var pins []string
+		for _, pin := range strings.Split(pinned, ";") {
+			hash, ok := strings.CutPrefix(strings.TrimSpace(pin), "sha256//")
+			if !ok {
+				return nil, fmt.Errorf("invalid pinned public key: %s", pin)
+			}
+			pins = append(pins, hash)
+		}
+		return pins, nil
+	}
+
+	data, err := os.ReadFile(pinned)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pinned public key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM public key found in %s", pinned)
+	}
+	spki := block.Bytes
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing pinned certificate: %v", err)
+		}
+		spki = cert.RawSubjectPublicKeyInfo
+	}
+	sum := sha256.Sum256(spki)
+	return []string{base64.StdEncoding.EncodeToString(sum[:])}, nil
+}
+
+// verifyPinnedKey checks that the public key of the server certificate matches one of the pins.
+func verifyPinnedKey(state tls.ConnectionState, pins []string) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("no certificate presented by %s", state.ServerName)
+	}
+	sum := sha256.Sum256(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+	hash := base64.StdEncoding.EncodeToString(sum[:])
+	for _, pin := range pins {
+		if pin == hash {
+			return nil
+		}
+	}
+	return fmt.Errorf("public key of %s (sha256//%s) does not match --pinnedpubkey", state.ServerName, hash)
+}
+
+// certificateError turns a certificate verification failure into a message naming
+// the host and the reason. Other errors are returned unchanged.
+func certificateError(host string, err error) error {
+	var reason string
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknownAuthority):
+		reason = "the certificate is issued by an unknown authority"
+	case errors.As(err, &hostnameErr):
+		reason = fmt.Sprintf("the certificate is not valid for this host (%v)", hostnameErr)
+	case errors.As(err, &invalidErr):
+		if invalidErr.Reason == x509.Expired {
+			reason = fmt.Sprintf("the certificate has expired or is not yet valid (now %s)", time.Now().Format("2006-01-02 15:04:05"))
+		} else {
+			reason = invalidErr.Error()
+		}
+	default:
+		var verifyErr *tls.CertificateVerificationError
+		if !errors.As(err, &verifyErr) {
+			return err
+		}
+		reason = verifyErr.Err.Error()
+	}
+	return &certificateVerifyError{host: host, reason: reason, err: err}
+}
+
+// certificateVerifyError reports a server certificate that failed verification.
+type certificateVerifyError struct {
+	host   string
+	reason string
+	err    error
+}
+
+func (e *certificateVerifyError) Error() string {
+	return fmt.Sprintf("cannot verify the certificate of %s: %s (use --no-check-certificate to connect insecurely)", e.host, e.reason)
+}
+
+func (e *certificateVerifyError) Unwrap() error {
+	return e.err
+}
@@ -58,10 +58,121 @@ func GetArgs() (string, string, int, bool, string, bool, bool, string, []string,
 	_mirror := flag.Bool("mirror", false, "Mirror site")
 	_logFile := flag.Bool("B", false, "Log file")
 	_UrlFile := flag.String("i", "", "Urls file")
-	_Exclude := flag.String("X", "", "exclude")
-	_Reject := flag.String("R", "", "reject")
+	_Exclude := flag.String("X", "", "Comma-separated directories to exclude")
+	_Include := flag.String("I", "", "Comma-separated directories to include")
+	_Reject := flag.String("R", "", "Comma-separated file name suffixes or patterns to reject")
+	_Accept := flag.String("A", "", "Comma-separated file name suffixes or patterns to accept")
+	flag.StringVar(&AcceptRegex, "accept-regex", "", "Regular expression the full URL of downloads must match")
+	flag.StringVar(&RejectRegex, "reject-regex", "", "Regular expression of full URLs not to download")
+	flag.BoolVar(&IgnoreCase, "ignore-case", false, "Match the accept, reject and directory filters case-insensitively")
+	flag.BoolVar(&Continue, "c", false, "Resume getting a partially-downloaded file")
+	flag.BoolVar(&Continue, "continue", false, "Resume getting a partially-downloaded file")
+	flag.IntVar(&Tries, "tries", Tries, "Number of attempts for each download (0 for unlimited)")
+	flag.IntVar(&WaitRetry, "waitretry", WaitRetry, "Maximum number of seconds to wait between retries")
+	flag.BoolVar(&RetryConnRefused, "retry-connrefused", false, "Retry connections refused by the server")
+	flag.IntVar(&MaxConcurrent, "max-concurrent", MaxConcurrent, "Number of -i downloads running at the same time")
+	flag.IntVar(&Segments, "segments", Segments, "Number of parallel connections for a single file")
+	_noCheckCertificate := flag.Bool("no-check-certificate", false, "Don't verify the server certificate")
+	flag.StringVar(&CACertificate, "ca-certificate", "", "File with the bundle of certificate authorities")
+	flag.StringVar(&CADirectory, "ca-directory", "", "Directory of certificate authorities")
+	flag.StringVar(&Certificate, "certificate", "", "Client certificate file (PEM)")
+	flag.StringVar(&PrivateKey, "private-key", "", "Client private key file (PEM)")
+	flag.StringVar(&PrivateKeyPassword, "private-key-password", "", "Password of an encrypted private key")
+	flag.StringVar(&SecureProtocol, "secure-protocol", SecureProtocol, "TLS versions: auto, MIN or MIN:MAX (TLSv1, TLSv1_1, TLSv1_2, TLSv1_3)")
+	flag.StringVar(&Ciphers, "ciphers", "", "Comma-separated TLS 1.2 cipher suites")
+	flag.StringVar(&PinnedPubKey, "pinnedpubkey", "", "Pinned server public key: sha256//<base64>[;...] or PEM file")
+	flag.IntVar(&MaxIdleConns, "max-idle-conns", MaxIdleConns, "Number of idle keep-alive connections kept open")
+	flag.IntVar(&MaxConnsPerHost, "max-conns-per-host", MaxConnsPerHost, "Maximum connections to a single host (0 for no limit)")
+	flag.StringVar(&HTTPProxy, "http-proxy", "", "Proxy for http URLs (overrides http_proxy)")
+	flag.StringVar(&HTTPSProxy, "https-proxy", "", "Proxy for https URLs (overrides https_proxy)")
+	flag.StringVar(&NoProxyHosts, "no-proxy-hosts", "", "Comma-separated hosts, domains and CIDRs reached without proxy (overrides no_proxy)")
+	flag.BoolVar(&NoProxy, "no-proxy", false, "Don't use proxies")
+	flag.StringVar(&ProxyUser, "proxy-user", "", "Proxy user name")
+	flag.StringVar(&ProxyPassword, "proxy-password", "", "Proxy password")
+	flag.StringVar(&Socks5, "socks5", "", "SOCKS5 proxy host:port")
+	flag.StringVar(&Socks5User, "socks5-user", "", "SOCKS5 proxy user name")
+	flag.StringVar(&Socks5Password, "socks5-password", "", "SOCKS5 proxy password")
+	flag.BoolVar(&Socks5LocalDNS, "socks5-local-dns", false, "Resolve host names locally instead of at the SOCKS5 proxy")
+	flag.StringVar(&LoadCookies, "load-cookies", "", "Load cookies from a Netscape cookies.txt file")
+	flag.StringVar(&SaveCookiesFile, "save-cookies", "", "Save cookies to a Netscape cookies.txt file")
+	flag.BoolVar(&KeepSessionCookies, "keep-session-cookies", false, "Also save session cookies")
+	flag.StringVar(&User, "user", "", "User name for HTTP authentication")
+	flag.StringVar(&Password, "password", "", "Password for HTTP authentication")
+	_askPassword := flag.Bool("ask-password", false, "Prompt for the HTTP authentication password")
+	flag.Var(&Headers, "header", "Extra \"Name: value\" request header (repeatable)")
+	flag.StringVar(&Method, "method", "", "HTTP method of the requests")
+	_postData := flag.String("post-data", "", "Send the string as a POST form")
+	_postFile := flag.String("post-file", "", "Send the content of the file as a POST form")
+	_bodyData := flag.String("body-data", "", "Send the string as the body of the --method request")
+	_bodyFile := flag.String("body-file", "", "Send the content of the file as the body of the --method request")
+	flag.StringVar(&UserAgent, "user-agent", UserAgent, "User-Agent header")
+	flag.StringVar(&Referer, "referer", "", "Referer header")
+	flag.IntVar(&MaxRedirect, "max-redirect", MaxRedirect, "Maximum number of redirects followed")
+	flag.BoolVar(&TrustServerNames, "trust-server-names", false, "Name files after the last URL of a redirect chain")
+	flag.BoolVar(&ContentDisposition, "content-disposition", false, "Name files after their Content-Disposition header")
+	flag.BoolVar(&Timestamping, "N", false, "Don't retrieve files unless newer than the local copy")
+	flag.BoolVar(&Timestamping, "timestamping", false, "Don't retrieve files unless newer than the local copy")
+	flag.BoolVar(&UseCache, "cache", false, "Reuse fresh downloads and revalidate stale ones with ETag and Last-Modified")
+	flag.StringVar(&CacheFile, "cache-file", CacheFile, "File holding the cache metadata")
+	flag.BoolVar(&NoClobber, "nc", false, "Skip downloads of files that already exist")
+	flag.BoolVar(&NoClobber, "no-clobber", false, "Skip downloads of files that already exist")
+	flag.IntVar(&Backups, "backups", 0, "Keep up to N numbered backups of overwritten files")
+	flag.StringVar(&Checksum, "checksum", "", "Expected digest of the download, as ALGORITHM=DIGEST (md5, sha1, sha256, sha512)")
+	flag.StringVar(&ChecksumsFile, "checksums", "", "File of expected digests by file name, such as SHA256SUMS")
+	flag.StringVar(&WriteSums, "write-sums", "", "Write the digests of the downloaded files to this manifest")
+	flag.StringVar(&SumsAlgorithm, "sums-algorithm", SumsAlgorithm, "Digest written by --write-sums")
+	flag.BoolVar(&ConvertLinks, "k", false, "Convert the links of mirrored pages for offline browsing")
+	flag.BoolVar(&ConvertLinks, "convert-links", false, "Convert the links of mirrored pages for offline browsing")
+	flag.IntVar(&Level, "l", 0, "Maximum depth of --mirror recursion (0 for no limit)")
+	flag.IntVar(&Level, "level", 0, "Maximum depth of --mirror recursion (0 for no limit)")
+	flag.BoolVar(&PageRequisites, "p", false, "Get all the images, style sheets and scripts needed to display the pages")
+	flag.BoolVar(&PageRequisites, "page-requisites", false, "Get all the images, style sheets and scripts needed to display the pages")
+	flag.BoolVar(&Verbose, "v", false, "Report the URLs left out by the filters")
+	flag.BoolVar(&Verbose, "verbose", false, "Report the URLs left out by the filters")
+	var commands stringList
+	flag.Var(&commands, "e", "Execute a wgetrc-style command such as robots=off (repeatable)")
+	_retryOnHTTPError := flag.String("retry-on-http-error", "", "Comma-separated HTTP status codes to retry")
 	flag.Parse()
 	output := *_output
+	outputName = output
+	CheckCertificate = !*_noCheckCertificate
+	err := setRequestOptions(*_postData, *_postFile, *_bodyData, *_bodyFile)
+	if err != nil {
+		fmt.Println("🚩 Error:", err)
+		return "", "", 0, false, "", false, true, "", nil, nil
+	}
+	if *_askPassword {
+		err := AskPassword()
+		if err != nil {
+			fmt.Println("🚩 Error:", err)
+			return "", "", 0, false, "", false, true, "", nil, nil
+		}
+	}
+	for _, command := range commands {
+		err = executeCommand(command)
+		if err != nil {
+			fmt.Println("🚩 Error:", err)
+			return "", "", 0, false, "", false, true, "", nil, nil
+		}
+	}
+	err = compileURLFilters()
+	if err != nil {
+		fmt.Println("🚩 Error:", err)
+		return "", "", 0, false, "", false, true, "", nil, nil
+	}
+	err = checkChecksumOptions(urlString, *_UrlFile == "" && !*_mirror && !PageRequisites)
+	if err != nil {
+		fmt.Println("🚩 Error:", err)
+		return "", "", 0, false, "", false, true, "", nil, nil
+	}
+	if *_retryOnHTTPError != "" {
+		codes, err := parseStatusCodes(*_retryOnHTTPError)
+		if err != nil {
+			fmt.Println("🚩 Error:", err)
+			return "", "", 0, false, "", false, true, "", nil, nil
+		}
+		RetryOnHTTPError = codes
+	}
 	rateLimit, err := convertFileSizeToBytes(*_rateLimit)
 	if err != nil {
 		fmt.Println("🚩 Error:", err)
@@ -74,6 +185,8 @@ func GetArgs() (string, string, int, bool, string, bool, bool, string, []string,
 	UrlFile := *_UrlFile
 	Reject := *_Reject
 	Exclude := *_Exclude
+	IncludeDirectories = strings.Split(*_Include, ",")
+	AcceptList = strings.Split(*_Accept, ",")
 	return urlString, output, rateLimit, logFile, downloadPath, mirror, false, UrlFile, strings.Split(Reject, ","), strings.Split(Exclude, ",")
 }
 
@@ -210,6 +323,23 @@ func convertFileSizeToBytes(fileSize string) (int, error) {
 	}
 }
 
+// parseStatusCodes parses a comma-separated list of HTTP status codes such as "429,503".
+func parseStatusCodes(list string) ([]int, error) {
+	var codes []int
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid HTTP status code: %s", field)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
 // downloadAndSaveResource downloads a resource from the given URL and saves it to the specified output file.
 //
 // Parameters:
@@ -1 +1,145 @@
 package wget
+
+// Options shared by the whole run. They are set once by GetArgs from the
+// command line flags and read by the download and mirroring code.
+var (
+	// Continue resumes a partially downloaded file instead of starting over.
+	Continue bool
+
+	// Tries is the number of attempts made for each download, 0 meaning unlimited.
+	Tries = 20
+	// WaitRetry is the maximum number of seconds to wait between two attempts.
+	WaitRetry = 10
+	// RetryOnHTTPError lists the HTTP status codes that are retried like network errors.
+	RetryOnHTTPError = []int{429, 500, 502, 503, 504}
+	// RetryConnRefused retries connections refused by the server, which are fatal otherwise.
+	RetryConnRefused bool
+
+	// Segments is the number of concurrent connections used to fetch a single file.
+	Segments = 1
+
+	// MaxConcurrent is the number of -i downloads running at the same time.
+	MaxConcurrent = 4
+
+	// CheckCertificate enables the verification of server certificates.
+	CheckCertificate = true
+	// CACertificate is a PEM bundle of extra certificate authorities to trust.
+	CACertificate string
+	// CADirectory is a directory of PEM certificate authorities to trust.
+	CADirectory string
+
+	// Certificate is the PEM client certificate presented to servers requiring mutual TLS.
+	Certificate string
+	// PrivateKey is the PEM private key of Certificate, read from Certificate when empty.
+	PrivateKey string
+	// PrivateKeyPassword decrypts an encrypted PrivateKey.
+	PrivateKeyPassword string
+	// SecureProtocol selects the TLS versions as "auto", "MIN" or "MIN:MAX".
+	SecureProtocol = "auto"
+	// Ciphers restricts the TLS 1.2 cipher suites to a comma-separated list.
+	Ciphers string
+	// PinnedPubKey pins the server public key by SPKI SHA-256 hash or PEM file.
+	PinnedPubKey string
+
+	// MaxIdleConns is the number of idle keep-alive connections kept for reuse.
+	MaxIdleConns = 100
+	// MaxConnsPerHost limits the connections opened to a single host, 0 meaning no limit.
+	MaxConnsPerHost = 0
+
+	// HTTPProxy and HTTPSProxy override the http_proxy and https_proxy environment variables.
+	HTTPProxy  string
+	HTTPSProxy string
+	// NoProxyHosts overrides the no_proxy environment variable.
+	NoProxyHosts string
+	// NoProxy disables proxies even when they are configured.
+	NoProxy bool
+	// ProxyUser and ProxyPassword authenticate to the proxy with Basic authentication.
+	ProxyUser     string
+	ProxyPassword string
+
+	// Socks5 is the host:port of a SOCKS5 proxy used for every connection.
+	Socks5 string
+	// Socks5User and Socks5Password authenticate to the SOCKS5 proxy.
+	Socks5User     string
+	Socks5Password string
+	// Socks5LocalDNS resolves host names locally instead of at the SOCKS5 proxy.
+	Socks5LocalDNS bool
+
+	// LoadCookies is a Netscape cookies.txt file loaded into the cookie jar.
+	LoadCookies string
+	// SaveCookiesFile is where the cookie jar is written in Netscape format at the end of the run.
+	SaveCookiesFile string
+	// KeepSessionCookies also saves the cookies that expire with the session.
+	KeepSessionCookies bool
+
+	// User and Password answer Basic and Digest challenges of the requested host.
+	User     string
+	Password string
+
+	// Method is the HTTP method of every request, GET unless a body is sent.
+	Method string
+	// RequestBody is the body sent with every request, from --post-data, --post-file,
+	// --body-data or --body-file.
+	RequestBody []byte
+	// Headers are the extra "Name: value" headers added to every request.
+	Headers stringList
+	// UserAgent is the User-Agent header sent to servers.
+	UserAgent = user_agent
+	// Referer is the Referer header sent to servers.
+	Referer string
+
+	// MaxRedirect is the number of redirects followed for a single request.
+	MaxRedirect = 20
+	// TrustServerNames names downloaded files after the last URL of a redirect chain.
+	TrustServerNames bool
+	// ContentDisposition names downloaded files after their Content-Disposition header.
+	ContentDisposition bool
+
+	// Timestamping skips files whose local copy is as recent as the server one (-N).
+	Timestamping bool
+
+	// UseCache keeps validators and expiry dates of downloads in CacheFile (--cache).
+	UseCache bool
+	// CacheFile is the JSON file holding the cache metadata.
+	CacheFile = ".wget-cache.json"
+
+	// NoClobber skips downloads whose file already exists (-nc).
+	NoClobber bool
+	// Backups is the number of numbered copies kept when a file is overwritten.
+	Backups int
+
+	// Checksum is the expected digest of the single URL downloaded, as ALGORITHM=DIGEST (--checksum).
+	Checksum string
+	// ChecksumsFile lists expected digests by file name, in the sha256sum format (--checksums).
+	ChecksumsFile string
+	// WriteSums is the manifest receiving the digests of the files downloaded in the run.
+	WriteSums string
+	// SumsAlgorithm is the digest written to WriteSums: md5, sha1, sha256 or sha512.
+	SumsAlgorithm = "sha256"
+
+	// ConvertLinks rewrites the links of mirrored pages for offline browsing (-k).
+	ConvertLinks bool
+	// Level is how many links deep --mirror follows pages, 0 for no limit (-l).
+	Level int
+	// PageRequisites fetches the images, style sheets and scripts of crawled pages even
+	// beyond Level (-p). Without --mirror, only the given page is fetched with them.
+	PageRequisites bool
+
+	// ExcludeDirectories are the URL directories never downloaded (-X).
+	ExcludeDirectories []string
+	// IncludeDirectories, when set, are the only URL directories downloaded (-I).
+	IncludeDirectories []string
+	// AcceptList, when set, holds the only file name suffixes or globs downloaded (-A).
+	AcceptList []string
+	// AcceptRegex, when set, is an RE2 expression the full URL of downloads must match.
+	AcceptRegex string
+	// RejectRegex is an RE2 expression of full URLs never downloaded.
+	RejectRegex string
+	// IgnoreCase makes the accept, reject and directory filters case-insensitive.
+	IgnoreCase bool
+	// Verbose reports the URLs left out by the filters.
+	Verbose bool
+
+	// Robots makes the crawl follow robots.txt and robots meta tags (-e robots=off to disable).
+	Robots = true
+)
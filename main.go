@@ -9,7 +9,8 @@ import (
 )
 
 func main() {
-	url, output, rateLimit, logFile, downloadPath, mirror, shouldReturn, UrlFile, reject, _ := wget.GetArgs()
+	url, output, rateLimit, logFile, downloadPath, mirror, shouldReturn, UrlFile, reject, exclude := wget.GetArgs()
+	wget.ExcludeDirectories = exclude
 	var lines []string
 	changeDisplay := false
 	if UrlFile != "" {
@@ -33,35 +34,40 @@ func main() {
 		return
 	}
 
-	var res []int
-	var finish string
-	var tabUrl []string
-
 	if logFile {
 		fmt.Println("Output will be written to ‘wget-log’.")
 	}
-	if !mirror {
-		for i := 0; i < len(lines); i++ {
-			url = lines[i]
-			wget.Domain = wget.GetDomain(url)
-			fileName, _ := wget.GetFilenameAndDirFromURL(url)
-			if output != "" {
-				fileName = output
-			}
-			resp, err, a, b, c := wget.DownloadAndSaveResource(url, fileName, downloadPath, reject, logFile, rateLimit, changeDisplay)
-			if err != nil {
-				fmt.Printf("Error downloading %s: %v, %v\n", url, err, resp)
-			}
-			res, finish, tabUrl = a, b, c
-		}
+	if !mirror && !wget.PageRequisites {
 		if changeDisplay {
-			fmt.Print("content size: ")
-			fmt.Println(res)
-			fmt.Println(finish)
-			fmt.Println(tabUrl)
+			results := wget.DownloadConcurrently(lines, output, downloadPath, reject, logFile, rateLimit)
+			wget.PrintSummary(results)
+		} else {
+			for i := 0; i < len(lines); i++ {
+				url = lines[i]
+				wget.Domain = wget.GetDomain(url)
+				fileName, _ := wget.GetFilenameAndDirFromURL(url)
+				if output != "" {
+					fileName = output
+				}
+				resp, err, _, _, _ := wget.DownloadAndSaveResource(url, fileName, downloadPath, reject, logFile, rateLimit, changeDisplay)
+				if err != nil {
+					fmt.Printf("Error downloading %s: %v, %v\n", url, err, resp)
+				}
+			}
 		}
-
-	} else {
+	} else if mirror {
 		wget.MirrorWebsite(url, downloadPath, reject, logFile, rateLimit)
+	} else {
+		wget.DownloadPages(lines, downloadPath, reject, logFile, rateLimit)
+	}
+
+	if err := wget.SaveCookies(); err != nil {
+		fmt.Println("🚩 Error:", err)
+	}
+	if err := wget.SaveCache(); err != nil {
+		fmt.Println("🚩 Error:", err)
+	}
+	if err := wget.WriteSumsFile(); err != nil {
+		fmt.Println("🚩 Error:", err)
 	}
 }